@@ -0,0 +1,163 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// API キーのハッシュの接頭辞
+const apiKeyHashPrefix = "sha256:"
+
+// API キーファイル
+type ApiKeysFile struct {
+	Keys []ApiKeyConfig `yaml:"keys"`
+}
+
+// API キーファイル（キー定義。キーそのものではなくハッシュを保存する）
+type ApiKeyConfig struct {
+	Subject string   `yaml:"subject"`
+	Groups  []string `yaml:"groups"`
+	// API キーの SHA-256（sha256:16 進数）
+	Hash string `yaml:"hash"`
+	// 有効期限（ローテーション時に古いキーに指定。省略時は無期限）
+	ExpiresAt *time.Time `yaml:"expires_at"`
+}
+
+// API キーのハッシュを生成
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return apiKeyHashPrefix + hex.EncodeToString(sum[:])
+}
+
+type apiKey struct {
+	subject   string
+	groups    []string
+	hash      []byte
+	expiresAt *time.Time
+}
+
+// API キーの保存先（ファイルは更新されると読み直す）
+type ApiKeyStore struct {
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	keys    []apiKey
+	envKeys []apiKey
+}
+
+// API キーの保存先を生成（env は「subject:sha256:16 進数」をカンマ区切りで指定）
+func NewApiKeyStore(path string, env string) (*ApiKeyStore, error) {
+	store := &ApiKeyStore{
+		path: path,
+	}
+	for _, v := range strings.Split(env, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("環境変数の API キー（%s）は subject:ハッシュ の形式で指定してください", v)
+		}
+		key, err := parseApiKey(ApiKeyConfig{Subject: parts[0], Hash: parts[1]})
+		if err != nil {
+			return nil, err
+		}
+		store.envKeys = append(store.envKeys, key)
+	}
+	if path != "" {
+		err := store.reload()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+func parseApiKey(cfg ApiKeyConfig) (apiKey, error) {
+	if cfg.Subject == "" {
+		return apiKey{}, fmt.Errorf("API キー（%s）の subject の指定がありません", cfg.Hash)
+	}
+	if !strings.HasPrefix(cfg.Hash, apiKeyHashPrefix) {
+		return apiKey{}, fmt.Errorf("API キー（%s）のハッシュは %s で始まる形式で指定してください", cfg.Subject, apiKeyHashPrefix)
+	}
+	hash, err := hex.DecodeString(strings.TrimPrefix(cfg.Hash, apiKeyHashPrefix))
+	if err != nil || len(hash) != sha256.Size {
+		return apiKey{}, fmt.Errorf("API キー（%s）のハッシュの形式が誤っています", cfg.Subject)
+	}
+	return apiKey{
+		subject:   cfg.Subject,
+		groups:    cfg.Groups,
+		hash:      hash,
+		expiresAt: cfg.ExpiresAt,
+	}, nil
+}
+
+// ファイルが更新されていれば読み直す
+func (s *ApiKeyStore) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("API キーファイル（%s）の読み込みに失敗しました : %s", s.path, err)
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("API キーファイル（%s）の読み込みに失敗しました : %s", s.path, err)
+	}
+	var file ApiKeysFile
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return fmt.Errorf("API キーファイル（%s）の形式が誤っています : %s", s.path, err)
+	}
+	var keys []apiKey
+	for _, v := range file.Keys {
+		key, err := parseApiKey(v)
+		if err != nil {
+			return fmt.Errorf("API キーファイル（%s）の形式が誤っています : %s", s.path, err)
+		}
+		keys = append(keys, key)
+	}
+	s.keys = keys
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// API キーで認証
+func (s *ApiKeyStore) Authenticate(key string) (*Principal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path != "" {
+		// 読み直しに失敗した場合は読み込み済みのキーで認証
+		err := s.reload()
+		if err != nil {
+			zap.L().Warn("failed to reload API keys file", zap.String("path", s.path), zap.Error(err))
+		}
+	}
+	sum := sha256.Sum256([]byte(key))
+	now := time.Now()
+	for _, v := range append(append([]apiKey(nil), s.envKeys...), s.keys...) {
+		if subtle.ConstantTimeCompare(sum[:], v.hash) != 1 {
+			continue
+		}
+		if v.expiresAt != nil && !now.Before(*v.expiresAt) {
+			return nil, fmt.Errorf("%w : API キー（%s）は有効期限切れです", ErrUnauthorized, v.subject)
+		}
+		return &Principal{
+			Subject: v.subject,
+			Groups:  v.groups,
+			Method:  AuthMethodApiKey,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w : API キーが誤っています", ErrUnauthorized)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	middleware "github.com/deepmap/oapi-codegen/pkg/gin-middleware"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// securitySchemes の名前（internal/set-release-tag.yaml）
+const (
+	SecuritySchemeApiKey = "apiKey"
+	SecuritySchemeBearer = "bearerAuth"
+)
+
+// API キーを指定するヘッダー
+const ApiKeyHeader = "X-API-Key"
+
+// 認証方式
+const (
+	AuthMethodApiKey = "api_key"
+	AuthMethodJwt    = "jwt"
+)
+
+// gin.Context に保存するキー
+const (
+	principalKey = "principal"
+	authErrorKey = "authError"
+)
+
+// 認証に失敗した場合のエラー
+var ErrUnauthorized = errors.New("認証に失敗しました")
+
+// 設定ファイル（認証）
+type AuthConfig struct {
+	// API キーのファイル
+	ApiKeysFile string `yaml:"api_keys_file"`
+	// JWT の検証
+	Jwt *JwtConfig `yaml:"jwt"`
+}
+
+// 認証済みの呼び出し元
+type Principal struct {
+	Subject string
+	Groups  []string
+	// 認証方式（api_key または jwt）
+	Method string
+}
+
+// 認証済みの呼び出し元を取得
+func PrincipalFromContext(c *gin.Context) (*Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := value.(*Principal)
+	return principal, ok
+}
+
+// API キー・JWT による認証
+type Authenticator struct {
+	apiKeys *ApiKeyStore
+	jwt     *JwtVerifier
+}
+
+// 認証の生成（apiKeysEnv は環境変数で指定した API キー。何も指定がなければ nil を返し、認証しない）
+func NewAuthenticator(cfg AuthConfig, apiKeysEnv string) (*Authenticator, error) {
+	authenticator := &Authenticator{}
+	if cfg.ApiKeysFile != "" || apiKeysEnv != "" {
+		apiKeys, err := NewApiKeyStore(cfg.ApiKeysFile, apiKeysEnv)
+		if err != nil {
+			return nil, err
+		}
+		authenticator.apiKeys = apiKeys
+	}
+	if cfg.Jwt != nil {
+		verifier, err := NewJwtVerifier(*cfg.Jwt)
+		if err != nil {
+			return nil, err
+		}
+		authenticator.jwt = verifier
+	}
+	if authenticator.apiKeys == nil && authenticator.jwt == nil {
+		return nil, nil
+	}
+	return authenticator, nil
+}
+
+// OapiRequestValidator の AuthenticationFunc（nil の場合は認証しない）
+func (a *Authenticator) AuthenticationFunc() openapi3filter.AuthenticationFunc {
+	if a == nil {
+		return openapi3filter.NoopAuthenticationFunc
+	}
+	return a.authenticate
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+	c := middleware.GetGinContext(ctx)
+	if c == nil {
+		return ErrUnauthorized
+	}
+	principal, err := a.principal(input)
+	if err != nil {
+		c.Set(authErrorKey, err)
+		return input.NewError(err)
+	}
+	c.Set(principalKey, principal)
+	return nil
+}
+
+func (a *Authenticator) principal(input *openapi3filter.AuthenticationInput) (*Principal, error) {
+	request := input.RequestValidationInput.Request
+	switch input.SecuritySchemeName {
+	case SecuritySchemeApiKey:
+		if a.apiKeys == nil {
+			return nil, fmt.Errorf("%w : API キーによる認証は設定されていません", ErrUnauthorized)
+		}
+		key := request.Header.Get(ApiKeyHeader)
+		if key == "" {
+			return nil, fmt.Errorf("%w : %s ヘッダーの指定がありません", ErrUnauthorized, ApiKeyHeader)
+		}
+		return a.apiKeys.Authenticate(key)
+	case SecuritySchemeBearer:
+		if a.jwt == nil {
+			return nil, fmt.Errorf("%w : JWT による認証は設定されていません", ErrUnauthorized)
+		}
+		authorization := request.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "Bearer ") {
+			return nil, fmt.Errorf("%w : Authorization ヘッダーに Bearer トークンの指定がありません", ErrUnauthorized)
+		}
+		return a.jwt.Verify(strings.TrimPrefix(authorization, "Bearer "))
+	}
+	return nil, fmt.Errorf("%w : 認証方式（%s）には対応していません", ErrUnauthorized, input.SecuritySchemeName)
+}
+
+// OapiRequestValidator の ErrorHandler（認証に失敗した場合は 401、それ以外は 400 を返す）
+func ValidationErrorHandler(c *gin.Context, message string, statusCode int) {
+	_, authenticated := PrincipalFromContext(c)
+	if value, ok := c.Get(authErrorKey); ok && !authenticated {
+		LoggerFromContext(c.Request.Context()).Info("authentication failed", zap.Any("error", value))
+		c.Header("WWW-Authenticate", `Bearer realm="set-release-tag"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, Error{
+			Message:   ErrUnauthorized.Error(),
+			RequestId: requestIdOf(c),
+		})
+		return
+	}
+	body := gin.H{"error": message}
+	if requestId := RequestId(c); requestId != "" {
+		body["request_id"] = requestId
+	}
+	c.AbortWithStatusJSON(statusCode, body)
+}
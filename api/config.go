@@ -0,0 +1,426 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// リリース申請の有効期限（デフォルト）
+const DefaultApprovalTtl = 24 * time.Hour
+
+// 付与するタグ（デフォルト）
+const DefaultTagName = "release"
+
+// 設定を上書きする環境変数の接頭辞（server.port は SET_RELEASE_TAG_SERVER_PORT で上書きする）
+const ConfigEnvPrefix = "SET_RELEASE_TAG_"
+
+// API キーを指定する環境変数（設定ファイルには書かない）
+const ApiKeysEnv = ConfigEnvPrefix + "API_KEYS"
+
+// 設定ファイル
+type Config struct {
+	TagName string `yaml:"tag_name"`
+	// 付与するタグのテンプレート（省略時は tag_name のみ）
+	Tags []string `yaml:"tags"`
+	// タグが変更不可（IMMUTABLE）のリポジトリでのリリース方法（fail または unique_tags、省略時は fail）
+	ImmutableStrategy RepositoryInfoImmutableStrategy `yaml:"immutable_strategy"`
+	DefaultRepository string                          `yaml:"default_repository"`
+	Repositories      []RepositoryConfig              `yaml:"repositories"`
+	// 認証（省略時は認証しない）
+	Auth AuthConfig `yaml:"auth"`
+	// 環境変数 SET_RELEASE_TAG_API_KEYS で指定した API キー
+	ApiKeys string `yaml:"-"`
+	// 権限定義（省略時はすべての操作を許可）
+	Policies []PolicyConfig `yaml:"policies"`
+	// リリース申請の有効期限（省略時は 24h）
+	ApprovalTtl time.Duration `yaml:"approval_ttl"`
+	// 予約リリースの実行予定日時を確認する間隔（省略時は 30s）
+	ScheduleInterval time.Duration `yaml:"schedule_interval"`
+	// リリース・ロールバック・タグ削除の結果の通知先（省略時は通知しない）
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+	Server   ServerConfig    `yaml:"server"`
+	Aws      AwsConfig       `yaml:"aws"`
+	Images   ImagesConfig    `yaml:"images"`
+	History  HistoryConfig   `yaml:"history"`
+	Log      LogConfig       `yaml:"log"`
+	Features FeaturesConfig  `yaml:"features"`
+
+	// 読み込んだ設定ファイル（デフォルト値のみの場合は空）
+	path string
+	// 設定ファイル・環境変数の読み込み時に見つかった誤り（Validate でまとめて返す）
+	problems []string
+}
+
+// 設定ファイル（リポジトリ定義）
+type RepositoryConfig struct {
+	Name string `yaml:"name"`
+	Uri  string `yaml:"uri"`
+	// リリースに申請者以外の承認を必要とする
+	RequireApproval bool `yaml:"require_approval"`
+}
+
+// 設定ファイル（HTTP サーバー。0 のタイムアウト・上限は制限しない）
+type ServerConfig struct {
+	Port int `yaml:"port"`
+	// リクエスト（ヘッダー・ボディ）の読み込みのタイムアウト
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+	// リクエストの読み込み後、レスポンスの書き込みまでのタイムアウト
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// Keep-Alive で次のリクエストを待つ時間
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// リクエストボディの上限（バイト）
+	MaxBodySize int64 `yaml:"max_body_size"`
+	// リクエスト・予約リリース 1 件あたりのタイムアウト
+	OperationTimeout time.Duration `yaml:"operation_timeout"`
+	// 停止時に処理中のリクエストの完了を待つ時間（0 の場合は完了するまで待つ）
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// /readyz の確認結果をキャッシュする時間
+	ReadinessTtl time.Duration `yaml:"readiness_ttl"`
+}
+
+// 設定ファイル（AWS）
+type AwsConfig struct {
+	// 認証情報の取得（AssumeRole など）に使うリージョン（ECR はリポジトリ URI のリージョンを使う。省略時は SDK のデフォルト）
+	Region string `yaml:"region"`
+	// 共有設定ファイル（~/.aws/config）のプロファイル（省略時は SDK のデフォルト）
+	Profile string `yaml:"profile"`
+	// ECR API 1 回あたり（SDK による再試行を含む）のタイムアウト
+	EcrCallTimeout time.Duration `yaml:"ecr_call_timeout"`
+}
+
+// 設定ファイル（コンテナイメージ一覧）
+type ImagesConfig struct {
+	// ECR DescribeImages の 1 ページあたりの取得件数（1 〜 1000）
+	PageSize int `yaml:"page_size"`
+	// 取得する件数の上限（0 は上限なし）
+	MaxImages int `yaml:"max_images"`
+	// キャッシュする時間（0 はキャッシュしない）
+	CacheTtl time.Duration `yaml:"cache_ttl"`
+}
+
+// 設定ファイル（リリース履歴）
+type HistoryConfig struct {
+	// リリース履歴を保存するファイル
+	Path string `yaml:"path"`
+	// ロールバックで連続して戻せるリリース数（リポジトリごと）
+	RollbackDepth int `yaml:"rollback_depth"`
+}
+
+// 設定ファイル（ログ）
+type LogConfig struct {
+	// json または console
+	Format string `yaml:"format"`
+	// debug・info・warn・error
+	Level string `yaml:"level"`
+}
+
+// 設定ファイル（機能の有効・無効）
+type FeaturesConfig struct {
+	// メトリクスの記録と /metrics
+	Metrics bool `yaml:"metrics"`
+	// リリース履歴の記録（無効にするとロールバック・リリース申請・予約リリースも使えない）
+	ReleaseHistory bool `yaml:"release_history"`
+	// 予約リリースの実行（リリース履歴が無効の場合は実行しない）
+	Scheduler bool `yaml:"scheduler"`
+}
+
+// 設定の誤り（見つかったすべての誤りを含む）
+type ConfigError struct {
+	// 設定ファイル（デフォルト値のみの場合は空）
+	Path     string
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	if e.Path != "" {
+		fmt.Fprintf(&b, "設定ファイル（%s）に %d 件の誤りがあります", e.Path, len(e.Problems))
+	} else {
+		fmt.Fprintf(&b, "設定に %d 件の誤りがあります", len(e.Problems))
+	}
+	for _, v := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(v)
+	}
+	return b.String()
+}
+
+// デフォルトの設定（リポジトリは含まない）
+func DefaultConfig() *Config {
+	return &Config{
+		TagName:           DefaultTagName,
+		ImmutableStrategy: Fail,
+		ApprovalTtl:       DefaultApprovalTtl,
+		ScheduleInterval:  DefaultScheduleInterval,
+		Server: ServerConfig{
+			Port:             18080,
+			ReadTimeout:      15 * time.Second,
+			WriteTimeout:     2 * time.Minute,
+			IdleTimeout:      2 * time.Minute,
+			MaxBodySize:      DefaultMaxBodySize,
+			OperationTimeout: DefaultOperationTimeout,
+			ShutdownTimeout:  30 * time.Second,
+			ReadinessTtl:     DefaultReadinessTtl,
+		},
+		Aws: AwsConfig{
+			EcrCallTimeout: DefaultEcrCallTimeout,
+		},
+		Images: ImagesConfig{
+			PageSize: int(EcrDescribeImagesMaxPageSize),
+			CacheTtl: DefaultImageCacheTtl,
+		},
+		History: HistoryConfig{
+			Path:          "set-release-tag.db",
+			RollbackDepth: DefaultRollbackDepth,
+		},
+		Log: LogConfig{
+			Format: "json",
+			Level:  "info",
+		},
+		Features: FeaturesConfig{
+			Metrics:        true,
+			ReleaseHistory: true,
+			Scheduler:      true,
+		},
+	}
+}
+
+// 設定ファイル読み込み（環境変数で上書きし、誤りがあれば *ConfigError を返す）
+func LoadConfig(path string) (*Config, error) {
+	cfg, err := ReadConfig(path, os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// 設定ファイル（path が空の場合はデフォルト値のみ）を読み込み、環境変数で上書き
+// （ファイルを読めない・YAML として解釈できない場合のみエラーを返す。項目の誤りは Validate でまとめて返す）
+func ReadConfig(path string, lookupEnv func(key string) (string, bool)) (*Config, error) {
+	cfg := DefaultConfig()
+	cfg.path = path
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("設定ファイル（%s）の読み込みに失敗しました : %s", path, err)
+		}
+		// 指定のない項目はデフォルト値のまま（存在しない項目・型の誤りは他の誤りとまとめて返す）
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(cfg)
+		var typeErr *yaml.TypeError
+		switch {
+		case err == nil, errors.Is(err, io.EOF):
+		case errors.As(err, &typeErr):
+			for _, v := range typeErr.Errors {
+				cfg.problems = append(cfg.problems, fmt.Sprintf("設定ファイルの形式が誤っています : %s", v))
+			}
+		default:
+			return nil, fmt.Errorf("設定ファイル（%s）の形式が誤っています : %s", path, err)
+		}
+	}
+	cfg.applyEnv(reflect.ValueOf(cfg).Elem(), ConfigEnvPrefix, lookupEnv)
+	if value, ok := lookupEnv(ApiKeysEnv); ok {
+		cfg.ApiKeys = value
+	}
+	return cfg, nil
+}
+
+// 環境変数による上書き（文字列・数値・真偽値・時間・文字列のリスト（カンマ区切り）の項目のみ。
+// 環境変数名は接頭辞と YAML のキーを _ でつないで大文字にしたもの）
+func (c *Config) applyEnv(value reflect.Value, prefix string, lookupEnv func(key string) (string, bool)) {
+	for i := 0; i < value.NumField(); i++ {
+		key, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + strings.ToUpper(key)
+		field := value.Field(i)
+		if field.Kind() == reflect.Struct {
+			c.applyEnv(field, name+"_", lookupEnv)
+			continue
+		}
+		env, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+		err := setConfigValue(field, env)
+		if err != nil {
+			c.problems = append(c.problems, fmt.Sprintf("環境変数 %s（%s）%s", name, env, err))
+		}
+	}
+}
+
+// 環境変数の値を設定項目に設定（形式が誤っている場合は理由を返す）
+func setConfigValue(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("は 30s・5m などの時間で指定してください")
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("は整数で指定してください")
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("は true または false で指定してください")
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return errors.New("は環境変数では指定できません（設定ファイルで指定してください）")
+		}
+		values := reflect.MakeSlice(field.Type(), 0, 0)
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if v != "" {
+				values = reflect.Append(values, reflect.ValueOf(v).Convert(field.Type().Elem()))
+			}
+		}
+		field.Set(values)
+	default:
+		return errors.New("は環境変数では指定できません（設定ファイルで指定してください）")
+	}
+	return nil
+}
+
+// 設定の確認（省略した tags・approval_ttl は補完する。誤りがあればすべてを *ConfigError で返す）
+func (c *Config) Validate() error {
+	problems := append([]string(nil), c.problems...)
+	invalid := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.TagName == "" {
+		c.TagName = DefaultTagName
+	}
+	if len(c.Tags) == 0 {
+		c.Tags = []string{c.TagName}
+	}
+	for _, v := range c.Tags {
+		err := ValidateTagTemplates([]string{v})
+		if err != nil {
+			invalid("tags が誤っています : %s", err)
+		}
+	}
+	if c.ApprovalTtl == 0 {
+		c.ApprovalTtl = DefaultApprovalTtl
+	}
+	if c.ApprovalTtl < 0 {
+		invalid("approval_ttl（%s）は正の値を指定してください", c.ApprovalTtl)
+	}
+	switch c.ImmutableStrategy {
+	case "":
+		c.ImmutableStrategy = Fail
+	case Fail:
+	case UniqueTags:
+		if len(FixedTagNames(c.Tags)) == len(c.Tags) {
+			invalid("immutable_strategy に %s を指定する場合は tags にプレースホルダーを含むタグを指定してください", UniqueTags)
+		}
+	default:
+		invalid("immutable_strategy（%s）は %s または %s を指定してください", c.ImmutableStrategy, Fail, UniqueTags)
+	}
+
+	// リポジトリ・認証・権限定義・通知先
+	for _, err := range validateRepositories(c.Repositories, c.DefaultRepository) {
+		invalid("%s", err)
+	}
+	defined := make(map[string]bool)
+	for _, v := range c.Repositories {
+		defined[v.Name] = true
+	}
+	for _, err := range validatePolicies(c.Policies, func(name string) bool { return defined[name] }) {
+		invalid("%s", err)
+	}
+	for _, err := range validateWebhooks(c.Webhooks) {
+		invalid("%s", err)
+	}
+	authenticator, err := NewAuthenticator(c.Auth, c.ApiKeys)
+	if err != nil {
+		invalid("auth が誤っています : %s", err)
+	} else if authenticator == nil {
+		// 申請者と承認者を区別できないため、認証なしでは承認を必須にできない
+		for _, v := range c.Repositories {
+			if v.RequireApproval {
+				invalid("リポジトリ（%s）の require_approval を指定する場合は auth（API キーまたは JWT）を設定してください", v.Name)
+			}
+		}
+	}
+
+	// HTTP サーバー
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		invalid("server.port（%d）は 1 〜 65535 の範囲で指定してください", c.Server.Port)
+	}
+	for _, v := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.operation_timeout", c.Server.OperationTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"server.readiness_ttl", c.Server.ReadinessTtl},
+		{"aws.ecr_call_timeout", c.Aws.EcrCallTimeout},
+		{"images.cache_ttl", c.Images.CacheTtl},
+	} {
+		if v.value < 0 {
+			invalid("%s（%s）は 0 以上の値を指定してください", v.key, v.value)
+		}
+	}
+	if c.Server.MaxBodySize < 0 {
+		invalid("server.max_body_size（%d）は 0 以上の値を指定してください", c.Server.MaxBodySize)
+	}
+
+	// AWS・コンテナイメージ一覧・リリース履歴・ログ
+	if c.Aws.Region != "" && !regionPattern.MatchString(c.Aws.Region) {
+		invalid("aws.region（%s）の形式が誤っています", c.Aws.Region)
+	}
+	if c.Images.PageSize < 1 || c.Images.PageSize > int(EcrDescribeImagesMaxPageSize) {
+		invalid("images.page_size（%d）は 1 〜 %d の範囲で指定してください", c.Images.PageSize, EcrDescribeImagesMaxPageSize)
+	}
+	if c.Images.MaxImages < 0 {
+		invalid("images.max_images（%d）は 0 以上の値を指定してください", c.Images.MaxImages)
+	}
+	if c.Features.ReleaseHistory && c.History.Path == "" {
+		invalid("history.path の指定がありません")
+	}
+	if c.History.RollbackDepth < 1 {
+		invalid("history.rollback_depth（%d）は 1 以上の値を指定してください", c.History.RollbackDepth)
+	}
+	if c.Features.ReleaseHistory && c.Features.Scheduler && c.ScheduleInterval <= 0 {
+		invalid("schedule_interval（%s）は 0 より大きい値を指定してください", c.ScheduleInterval)
+	}
+	_, err = NewLogger(c.Log.Format, c.Log.Level)
+	if err != nil {
+		invalid("log が誤っています : %s", err)
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Path: c.path, Problems: problems}
+	}
+	return nil
+}
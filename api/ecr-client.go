@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+)
+
+// リージョンの ECR クライアントの提供元（テストではモックを返すものに差し替える）
+type EcrClientProvider interface {
+	Client(region string) (ECRAPI, error)
+}
+
+// 認証情報を取得できるかの確認（EcrClientProvider が実装している場合は /readyz で確認する）
+type CredentialsChecker interface {
+	CheckCredentials(ctx context.Context) error
+}
+
+// リージョンごとに ECR クライアントを 1 つだけ生成して使い回す
+// （AWS の設定は起動時に 1 回だけ読み込み、認証情報は全リージョンで共有する SDK のキャッシュで期限前に更新する）
+type EcrClientFactory struct {
+	cfg     aws.Config
+	lock    sync.Mutex
+	clients map[string]*ecr.Client
+}
+
+// awsConfig のリージョン・プロファイルが空の場合は SDK のデフォルト（環境変数 AWS_REGION・AWS_PROFILE など）を使う
+func NewEcrClientFactory(ctx context.Context, awsConfig AwsConfig) (*EcrClientFactory, error) {
+	var options []func(*config.LoadOptions) error
+	if awsConfig.Region != "" {
+		options = append(options, config.WithRegion(awsConfig.Region))
+	}
+	if awsConfig.Profile != "" {
+		options = append(options, config.WithSharedConfigProfile(awsConfig.Profile))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("AWS（API）の設定の読み込みに失敗しました : %s", err)
+	}
+	return &EcrClientFactory{
+		cfg:     cfg,
+		clients: make(map[string]*ecr.Client),
+	}, nil
+}
+
+// リージョンの ECR クライアント（初回のみ生成）
+func (f *EcrClientFactory) Client(region string) (ECRAPI, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	client, ok := f.clients[region]
+	if !ok {
+		client = ecr.NewFromConfig(f.cfg, func(o *ecr.Options) {
+			o.Region = region
+		})
+		f.clients[region] = client
+	}
+	return client, nil
+}
+
+// 認証情報を取得できるか確認（キャッシュが有効な間は取得し直さない）
+func (f *EcrClientFactory) CheckCredentials(ctx context.Context) error {
+	if f.cfg.Credentials == nil {
+		return errors.New("AWS の認証情報が設定されていません")
+	}
+	_, err := f.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("AWS の認証情報を取得できません : %s", err)
+	}
+	return nil
+}
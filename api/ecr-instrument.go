@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/smithy-go"
+	"go.uber.org/zap"
+)
+
+// ECR API の呼び出しをログ・メトリクスに記録するクライアント
+type instrumentedECR struct {
+	api     ECRAPI
+	metrics *Metrics
+}
+
+// ECR クライアントの呼び出しをログ（context のロガー）・メトリクス（metrics が nil の場合は記録しない）に記録するようにする
+func InstrumentECR(api ECRAPI, metrics *Metrics) ECRAPI {
+	return instrumentedECR{api: api, metrics: metrics}
+}
+
+// エラーコード（ThrottlingException など。ECR API のエラーでない場合は Unknown）
+func ecrErrorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return "Unknown"
+}
+
+// 成功した呼び出しは debug、失敗した呼び出しは warn で出力
+func (e instrumentedECR) observe(ctx context.Context, method string, start time.Time, err error) {
+	duration := time.Since(start)
+	var code string
+	logger := LoggerFromContext(ctx)
+	if err != nil {
+		code = ecrErrorCode(err)
+		logger.Warn("ecr call failed", zap.String("ecr_method", method), zap.Duration("duration", duration), zap.String("error_code", code), zap.Error(err))
+	} else {
+		logger.Debug("ecr call", zap.String("ecr_method", method), zap.Duration("duration", duration))
+	}
+	if e.metrics != nil {
+		e.metrics.observeECR(method, duration, code)
+	}
+}
+
+func (e instrumentedECR) DescribeImages(ctx context.Context, params *ecr.DescribeImagesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
+	start := time.Now()
+	output, err := e.api.DescribeImages(ctx, params, optFns...)
+	e.observe(ctx, "DescribeImages", start, err)
+	return output, err
+}
+
+func (e instrumentedECR) BatchGetImage(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error) {
+	start := time.Now()
+	output, err := e.api.BatchGetImage(ctx, params, optFns...)
+	e.observe(ctx, "BatchGetImage", start, err)
+	return output, err
+}
+
+func (e instrumentedECR) PutImage(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error) {
+	start := time.Now()
+	output, err := e.api.PutImage(ctx, params, optFns...)
+	e.observe(ctx, "PutImage", start, err)
+	return output, err
+}
+
+func (e instrumentedECR) BatchDeleteImage(ctx context.Context, params *ecr.BatchDeleteImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error) {
+	start := time.Now()
+	output, err := e.api.BatchDeleteImage(ctx, params, optFns...)
+	e.observe(ctx, "BatchDeleteImage", start, err)
+	return output, err
+}
+
+func (e instrumentedECR) DescribeRepositories(ctx context.Context, params *ecr.DescribeRepositoriesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
+	start := time.Now()
+	output, err := e.api.DescribeRepositories(ctx, params, optFns...)
+	e.observe(ctx, "DescribeRepositories", start, err)
+	return output, err
+}
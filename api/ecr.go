@@ -2,12 +2,12 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
 	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
 )
@@ -17,35 +17,78 @@ type ECRAPI interface {
 	EcrDescribeImagesAPI
 	EcrBatchGetImageAPI
 	EcrPutImageAPI
+	EcrBatchDeleteImageAPI
+	EcrDescribeRepositoriesAPI
 }
 
-// ECR クライアント生成
-func EcrClient(region string) (*ecr.Client, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
-	if err != nil {
-		return nil, fmt.Errorf("AWS（API）の認証に失敗しました : %s", err)
-	}
-	return ecr.NewFromConfig(cfg), nil
-}
+// タグ削除時のエラー
+var (
+	ErrImageTagNotFound = errors.New("対象のタグを持つイメージが存在しません")
+	ErrLastImageTag     = errors.New("イメージの最後のタグは外せません（外す場合は force を指定してください）")
+)
+
+// 付け替えない指定のタグが既に別のイメージに付いている場合のエラー
+var ErrTagInUse = errors.New("タグが既に別のイメージに付いています")
 
 // ECR DescribeImages
 type EcrDescribeImagesAPI interface {
 	DescribeImages(ctx context.Context, params *ecr.DescribeImagesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error)
 }
 
-func EcrDescribeImages(ctx context.Context, api EcrDescribeImagesAPI, repositoryName string, registryId string) ([]types.ImageDetail, error) {
-	// ページネーションさせないために最大件数を 1,000 に（実際には数十個程度の想定）
-	maxResults := int32(1000)
+// ECR DescribeImages の 1 ページあたりの取得件数の上限（API 仕様）
+const EcrDescribeImagesMaxPageSize = int32(1000)
 
+func EcrDescribeImages(ctx context.Context, api EcrDescribeImagesAPI, repositoryName string, registryId string, pageSize int32, maxImages int) ([]types.ImageDetail, error) {
+	if pageSize < 1 || pageSize > EcrDescribeImagesMaxPageSize {
+		return nil, fmt.Errorf("ページサイズ（%d）は 1 〜 %d の範囲で指定してください", pageSize, EcrDescribeImagesMaxPageSize)
+	}
+
+	// NextToken が返らなくなるまで全ページを取得（maxImages が 1 以上なら取得件数の上限とする）
+	var imageDetails []types.ImageDetail
+	var nextToken *string
+	for {
+		ecrImages, err := api.DescribeImages(ctx, &ecr.DescribeImagesInput{
+			RepositoryName: aws.String(repositoryName),
+			RegistryId:     aws.String(registryId),
+			MaxResults:     aws.Int32(pageSize),
+			NextToken:      nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("リポジトリ（%s）のイメージ詳細一覧の取得に失敗しました : %w", repositoryName, err)
+		}
+		imageDetails = append(imageDetails, ecrImages.ImageDetails...)
+		if maxImages > 0 && len(imageDetails) >= maxImages {
+			imageDetails = imageDetails[:maxImages]
+			break
+		}
+		nextToken = ecrImages.NextToken
+		if aws.ToString(nextToken) == "" {
+			break
+		}
+	}
+	return imageDetails, nil
+}
+
+// 指定したイメージの詳細を取得（イメージが存在しなければ nil）
+func EcrDescribeImage(ctx context.Context, api EcrDescribeImagesAPI, repositoryName string, registryId string, imageId types.ImageIdentifier) (*types.ImageDetail, error) {
+	var imageIds []types.ImageIdentifier
+	imageIds = append(imageIds, imageId)
 	ecrImages, err := api.DescribeImages(ctx, &ecr.DescribeImagesInput{
 		RepositoryName: aws.String(repositoryName),
 		RegistryId:     aws.String(registryId),
-		MaxResults:     aws.Int32(maxResults),
+		ImageIds:       imageIds,
 	})
+	var notFound *types.ImageNotFoundException
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("リポジトリ（%s）のイメージ詳細一覧の取得に失敗しました : %s", repositoryName, err)
+		return nil, fmt.Errorf("リポジトリ（%s）のイメージ（%s）の詳細の取得に失敗しました : %w", repositoryName, imageIdString(imageId), err)
+	}
+	if len(ecrImages.ImageDetails) == 0 {
+		return nil, nil
 	}
-	return ecrImages.ImageDetails, nil
+	return &ecrImages.ImageDetails[0], nil
 }
 
 // ECR BatchGetImage
@@ -53,46 +96,156 @@ type EcrBatchGetImageAPI interface {
 	BatchGetImage(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error)
 }
 
-func EcrBatchGetImage(ctx context.Context, api EcrBatchGetImageAPI, repositoryName string, registryId string, selectedTagName string) ([]types.Image, error) {
+// BatchGetImage で受け付けるマニフェストのメディアタイプ（マルチアーキテクチャのマニフェストリスト・イメージインデックスを含む）
+var AcceptedManifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+// イメージ識別子の表示用文字列（タグを優先）
+func imageIdString(imageId types.ImageIdentifier) string {
+	if imageId.ImageTag != nil {
+		return aws.ToString(imageId.ImageTag)
+	}
+	return aws.ToString(imageId.ImageDigest)
+}
+
+func EcrBatchGetImage(ctx context.Context, api EcrBatchGetImageAPI, repositoryName string, registryId string, imageId types.ImageIdentifier) ([]types.Image, error) {
 	var imageIds []types.ImageIdentifier
-	imageIds = append(imageIds, types.ImageIdentifier{
-		ImageTag: aws.String(selectedTagName),
-	})
+	imageIds = append(imageIds, imageId)
 	ecrImage, err := api.BatchGetImage(ctx, &ecr.BatchGetImageInput{
-		ImageIds:       imageIds,
-		RepositoryName: aws.String(repositoryName),
-		RegistryId:     aws.String(registryId),
+		ImageIds:           imageIds,
+		RepositoryName:     aws.String(repositoryName),
+		RegistryId:         aws.String(registryId),
+		AcceptedMediaTypes: AcceptedManifestMediaTypes,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("リポジトリ（%s）のイメージ情報の取得に失敗しました : %s", repositoryName, err)
+		return nil, fmt.Errorf("リポジトリ（%s）のイメージ情報の取得に失敗しました : %w", repositoryName, err)
 	}
 	if ecrImage == nil {
-		return nil, fmt.Errorf("リポジトリ（%s）のイメージ情報の取得に失敗しました : 対象のイメージ（%s）が存在しません", repositoryName, selectedTagName)
+		return nil, fmt.Errorf("リポジトリ（%s）のイメージ情報の取得に失敗しました : 対象のイメージ（%s）が存在しません", repositoryName, imageIdString(imageId))
 	}
 
 	var images []types.Image
 	images = ecrImage.Images
 	if len(images) == 0 {
-		return nil, fmt.Errorf("リポジトリ（%s）のイメージ情報の取得に失敗しました : 対象のイメージ（%s）が存在しません", repositoryName, selectedTagName)
+		return nil, fmt.Errorf("リポジトリ（%s）のイメージ情報の取得に失敗しました : 対象のイメージ（%s）が存在しません", repositoryName, imageIdString(imageId))
 	}
 	return images, nil
 }
 
+// 指定タグを持つイメージのダイジェストを取得（タグを持つイメージがなければ空文字列）
+func EcrGetTaggedDigest(ctx context.Context, api EcrBatchGetImageAPI, repositoryName string, registryId string, tagName string) (string, error) {
+	var imageIds []types.ImageIdentifier
+	imageIds = append(imageIds, types.ImageIdentifier{
+		ImageTag: aws.String(tagName),
+	})
+	ecrImage, err := api.BatchGetImage(ctx, &ecr.BatchGetImageInput{
+		ImageIds:           imageIds,
+		RepositoryName:     aws.String(repositoryName),
+		RegistryId:         aws.String(registryId),
+		AcceptedMediaTypes: AcceptedManifestMediaTypes,
+	})
+	if err != nil {
+		return "", fmt.Errorf("リポジトリ（%s）のタグ（%s）を持つイメージの取得に失敗しました : %w", repositoryName, tagName, err)
+	}
+	if ecrImage == nil {
+		return "", nil
+	}
+	for _, v := range ecrImage.Failures {
+		if v.FailureCode == types.ImageFailureCodeImageNotFound {
+			return "", nil
+		}
+		return "", fmt.Errorf("リポジトリ（%s）のタグ（%s）を持つイメージの取得に失敗しました : %s", repositoryName, tagName, aws.ToString(v.FailureReason))
+	}
+	if len(ecrImage.Images) == 0 || ecrImage.Images[0].ImageId == nil {
+		return "", nil
+	}
+	return aws.ToString(ecrImage.Images[0].ImageId.ImageDigest), nil
+}
+
 // ECR PutImage
 type EcrPutImageAPI interface {
 	PutImage(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error)
 }
 
-func EcrPutImage(ctx context.Context, api EcrPutImageAPI, imageManifest string, repositoryName string, registryId string, attachTagName string) error {
-	_, err := api.PutImage(ctx, &ecr.PutImageInput{
+// imageManifestMediaType は BatchGetImage で取得したものをそのまま渡す（空なら ECR がマニフェストから判定）
+func EcrPutImage(ctx context.Context, api EcrPutImageAPI, imageManifest string, imageManifestMediaType string, repositoryName string, registryId string, attachTagName string) error {
+	input := &ecr.PutImageInput{
 		ImageManifest:  aws.String(imageManifest),
 		RepositoryName: aws.String(repositoryName),
 		ImageTag:       aws.String(attachTagName),
 		RegistryId:     aws.String(registryId),
-	})
+	}
+	if imageManifestMediaType != "" {
+		input.ImageManifestMediaType = aws.String(imageManifestMediaType)
+	}
+	_, err := api.PutImage(ctx, input)
 	return err
 }
 
+// ECR BatchDeleteImage
+type EcrBatchDeleteImageAPI interface {
+	BatchDeleteImage(ctx context.Context, params *ecr.BatchDeleteImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error)
+}
+
+func EcrBatchDeleteImage(ctx context.Context, api EcrBatchDeleteImageAPI, repositoryName string, registryId string, imageId types.ImageIdentifier) error {
+	var imageIds []types.ImageIdentifier
+	imageIds = append(imageIds, imageId)
+	output, err := api.BatchDeleteImage(ctx, &ecr.BatchDeleteImageInput{
+		ImageIds:       imageIds,
+		RepositoryName: aws.String(repositoryName),
+		RegistryId:     aws.String(registryId),
+	})
+	if err != nil {
+		return fmt.Errorf("リポジトリ（%s）のイメージ（%s）の削除に失敗しました : %w", repositoryName, imageIdString(imageId), err)
+	}
+	for _, v := range output.Failures {
+		return fmt.Errorf("リポジトリ（%s）のイメージ（%s）の削除に失敗しました : %s", repositoryName, imageIdString(imageId), aws.ToString(v.FailureReason))
+	}
+	return nil
+}
+
+// ECR DescribeRepositories
+type EcrDescribeRepositoriesAPI interface {
+	DescribeRepositories(ctx context.Context, params *ecr.DescribeRepositoriesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error)
+}
+
+func EcrDescribeRepository(ctx context.Context, api EcrDescribeRepositoriesAPI, repositoryName string, registryId string) (*types.Repository, error) {
+	output, err := api.DescribeRepositories(ctx, &ecr.DescribeRepositoriesInput{
+		RepositoryNames: []string{repositoryName},
+		RegistryId:      aws.String(registryId),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("リポジトリ（%s）の情報の取得に失敗しました : %w", repositoryName, err)
+	}
+	if len(output.Repositories) == 0 {
+		return nil, fmt.Errorf("リポジトリ（%s）が存在しません", repositoryName)
+	}
+	return &output.Repositories[0], nil
+}
+
+// リポジトリのタグの変更可否を取得
+func TagMutability(ctx context.Context, api EcrDescribeRepositoriesAPI, repositoryUri string) (types.ImageTagMutability, error) {
+	uri, err := ParseRepositoryUri(repositoryUri)
+	if err != nil {
+		return "", err
+	}
+	repositoryName := uri.Name
+	registryId := uri.RegistryId
+
+	repository, err := EcrDescribeRepository(ctx, api, repositoryName, registryId)
+	if err != nil {
+		return "", err
+	}
+	if repository.ImageTagMutability == "" {
+		return types.ImageTagMutabilityMutable, nil
+	}
+	return repository.ImageTagMutability, nil
+}
+
 // ImageList を取得
 func GetImageList(imageDetails []types.ImageDetail, repositoryName string, repositoryUri string) []Image {
 	var imageList []Image
@@ -122,11 +275,15 @@ func GetImageList(imageDetails []types.ImageDetail, repositoryName string, repos
 }
 
 // ECR リポジトリ内イメージ一覧取得
-func ImageList(ctx context.Context, api ECRAPI, repositoryUri string) ([]Image, error) {
-	repositoryName := strings.Split(repositoryUri, "/")[1]
-	registryId := strings.Split(repositoryUri, ".")[0]
+func ImageList(ctx context.Context, api ECRAPI, repositoryUri string, pageSize int32, maxImages int) ([]Image, error) {
+	uri, err := ParseRepositoryUri(repositoryUri)
+	if err != nil {
+		return nil, err
+	}
+	repositoryName := uri.Name
+	registryId := uri.RegistryId
 
-	imageDetails, err := EcrDescribeImages(ctx, api, repositoryName, registryId)
+	imageDetails, err := EcrDescribeImages(ctx, api, repositoryName, registryId, pageSize, maxImages)
 	if err != nil {
 		return nil, err
 	}
@@ -135,17 +292,216 @@ func ImageList(ctx context.Context, api ECRAPI, repositoryUri string) ([]Image,
 	return imageList, nil
 }
 
-// 対象タグを持つイメージにリリースタグを付加
-func SetTag(ctx context.Context, api ECRAPI, repositoryUri string, attachTagName string, selectedTagName string) error {
-	repositoryName := strings.Split(repositoryUri, "/")[1]
-	registryId := strings.Split(repositoryUri, ".")[0]
+// タグごとの付け替え結果
+type TagApplyResult struct {
+	Tag string
+	// 設定前にタグを持っていたイメージのダイジェスト（なければ空文字列）
+	PreviousDigest string
+	// 既に対象イメージにタグが付いていて変更しなかった場合は true
+	Unchanged bool
+	// 付け替えに失敗した場合のエラー
+	Err error
+}
+
+// リリースタグ設定結果
+type TagResult struct {
+	// 設定前に先頭のタグを持っていたイメージのダイジェスト（なければ空文字列）
+	PreviousDigest string
+	// 設定後にタグを持つイメージのダイジェスト
+	Digest string
+	// 先頭のタグが既に対象イメージに付いていて何も変更しなかった場合は true
+	Unchanged bool
+	// タグごとの結果（指定した順）
+	Tags []TagApplyResult
+}
+
+// 付与できたタグ（変更不要だったものを含む）
+func (r *TagResult) AppliedTags() []string {
+	tags := []string{}
+	for _, v := range r.Tags {
+		if v.Err == nil {
+			tags = append(tags, v.Tag)
+		}
+	}
+	return tags
+}
 
-	images, err := EcrBatchGetImage(ctx, api, repositoryName, registryId, selectedTagName)
+// 付与できなかったタグ
+func (r *TagResult) FailedTags() []string {
+	tags := []string{}
+	for _, v := range r.Tags {
+		if v.Err != nil {
+			tags = append(tags, v.Tag)
+		}
+	}
+	return tags
+}
+
+// 一部のタグの付与に失敗した場合のエラー
+type TagApplyError struct {
+	Result *TagResult
+}
+
+func (e *TagApplyError) Error() string {
+	var messages []string
+	for _, v := range e.Result.Tags {
+		if v.Err != nil {
+			messages = append(messages, fmt.Sprintf("%s : %s", v.Tag, v.Err))
+		}
+	}
+	return fmt.Sprintf("タグ（%s）の付与に失敗しました（付与済み : %s） : %s", strings.Join(e.Result.FailedTags(), ", "), strings.Join(e.Result.AppliedTags(), ", "), strings.Join(messages, " / "))
+}
+
+// 対象イメージ（タグまたはダイジェストで指定）のダイジェストを取得
+func ResolveDigest(ctx context.Context, api EcrBatchGetImageAPI, repositoryUri string, selected types.ImageIdentifier) (string, error) {
+	uri, err := ParseRepositoryUri(repositoryUri)
 	if err != nil {
-		return err
+		return "", err
 	}
+	repositoryName := uri.Name
+	registryId := uri.RegistryId
 
-	imageManifest := *images[0].ImageManifest
-	err = EcrPutImage(ctx, api, imageManifest, repositoryName, registryId, attachTagName)
-	return err
+	images, err := EcrBatchGetImage(ctx, api, repositoryName, registryId, selected)
+	if err != nil {
+		return "", err
+	}
+	if images[0].ImageId == nil {
+		return "", fmt.Errorf("リポジトリ（%s）のイメージ（%s）のダイジェストが取得できません", repositoryName, imageIdString(selected))
+	}
+	return aws.ToString(images[0].ImageId.ImageDigest), nil
+}
+
+// 対象イメージと、付け替え前に各タグを持っているイメージを取得
+func resolveTag(ctx context.Context, api ECRAPI, repositoryName string, registryId string, attachTagNames []string, selected types.ImageIdentifier) (*types.Image, *TagResult, error) {
+	if len(attachTagNames) == 0 {
+		return nil, nil, errors.New("付与するタグの指定がありません")
+	}
+	images, err := EcrBatchGetImage(ctx, api, repositoryName, registryId, selected)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &TagResult{}
+	if images[0].ImageId != nil {
+		result.Digest = aws.ToString(images[0].ImageId.ImageDigest)
+	}
+	// 付け替え前にタグを持っているイメージを記録
+	for _, v := range attachTagNames {
+		previousDigest, err := EcrGetTaggedDigest(ctx, api, repositoryName, registryId, v)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Tags = append(result.Tags, TagApplyResult{
+			Tag:            v,
+			PreviousDigest: previousDigest,
+			Unchanged:      previousDigest != "" && previousDigest == result.Digest,
+		})
+	}
+	result.PreviousDigest = result.Tags[0].PreviousDigest
+	result.Unchanged = result.Tags[0].Unchanged
+	return &images[0], result, nil
+}
+
+// タグの付け替え内容を確認（PutImage は呼ばない）
+func PlanTag(ctx context.Context, api ECRAPI, repositoryUri string, attachTagNames []string, selected types.ImageIdentifier) (*TagResult, error) {
+	uri, err := ParseRepositoryUri(repositoryUri)
+	if err != nil {
+		return nil, err
+	}
+	repositoryName := uri.Name
+	registryId := uri.RegistryId
+
+	_, result, err := resolveTag(ctx, api, repositoryName, registryId, attachTagNames, selected)
+	return result, err
+}
+
+// 対象イメージ（タグまたはダイジェストで指定）に指定したタグをすべて付加
+// （先頭のタグが既に対象イメージに付いている場合は何もしない。一部のタグの付与に失敗した場合は *TagApplyError を返す）
+func SetTag(ctx context.Context, api ECRAPI, repositoryUri string, attachTagNames []string, selected types.ImageIdentifier) (*TagResult, error) {
+	return SetTagExclusive(ctx, api, repositoryUri, attachTagNames, nil, selected)
+}
+
+// SetTag と同じ（exclusiveTagNames のタグが既に別のイメージに付いている場合は、どのタグも付け替えずに ErrTagInUse を返す）
+func SetTagExclusive(ctx context.Context, api ECRAPI, repositoryUri string, attachTagNames []string, exclusiveTagNames []string, selected types.ImageIdentifier) (*TagResult, error) {
+	uri, err := ParseRepositoryUri(repositoryUri)
+	if err != nil {
+		return nil, err
+	}
+	repositoryName := uri.Name
+	registryId := uri.RegistryId
+
+	image, result, err := resolveTag(ctx, api, repositoryName, registryId, attachTagNames, selected)
+	if err != nil {
+		return nil, err
+	}
+	if result.Unchanged {
+		// 既に対象イメージにリリースタグが付いている
+		return result, nil
+	}
+	for _, v := range result.Tags {
+		if contains(exclusiveTagNames, v.Tag) && v.PreviousDigest != "" && v.PreviousDigest != result.Digest {
+			return nil, fmt.Errorf("リポジトリ（%s）のタグ（%s）はイメージ（%s）に付いています : %w", repositoryName, v.Tag, v.PreviousDigest, ErrTagInUse)
+		}
+	}
+
+	imageManifest := aws.ToString(image.ImageManifest)
+	imageManifestMediaType := aws.ToString(image.ImageManifestMediaType)
+	failed := false
+	for i, v := range result.Tags {
+		if v.Unchanged {
+			continue
+		}
+		err = EcrPutImage(ctx, api, imageManifest, imageManifestMediaType, repositoryName, registryId, v.Tag)
+		var alreadyExists *types.ImageAlreadyExistsException
+		if errors.As(err, &alreadyExists) {
+			// 確認後に別の呼び出しで同じイメージにタグが付いた場合
+			result.Tags[i].PreviousDigest = result.Digest
+			result.Tags[i].Unchanged = true
+			continue
+		}
+		if err != nil {
+			result.Tags[i].Err = err
+			failed = true
+		}
+	}
+	result.PreviousDigest = result.Tags[0].PreviousDigest
+	result.Unchanged = result.Tags[0].Unchanged
+	if failed {
+		return result, &TagApplyError{Result: result}
+	}
+	return result, nil
+}
+
+// イメージからタグを外す（イメージの最後のタグは force 指定時のみ外し、その場合イメージも削除される）
+func RemoveTag(ctx context.Context, api ECRAPI, repositoryUri string, tagName string, force bool) (*TagResult, error) {
+	uri, err := ParseRepositoryUri(repositoryUri)
+	if err != nil {
+		return nil, err
+	}
+	repositoryName := uri.Name
+	registryId := uri.RegistryId
+
+	imageId := types.ImageIdentifier{
+		ImageTag: aws.String(tagName),
+	}
+	imageDetail, err := EcrDescribeImage(ctx, api, repositoryName, registryId, imageId)
+	if err != nil {
+		return nil, err
+	}
+	if imageDetail == nil {
+		return nil, fmt.Errorf("リポジトリ（%s）のタグ（%s） : %w", repositoryName, tagName, ErrImageTagNotFound)
+	}
+	if len(imageDetail.ImageTags) <= 1 && !force {
+		return nil, fmt.Errorf("リポジトリ（%s）のタグ（%s） : %w", repositoryName, tagName, ErrLastImageTag)
+	}
+
+	// タグのみを指定して削除すると、他のタグが残っている場合はタグだけが外れる
+	err = EcrBatchDeleteImage(ctx, api, repositoryName, registryId, imageId)
+	if err != nil {
+		return nil, err
+	}
+	result := &TagResult{
+		PreviousDigest: aws.ToString(imageDetail.ImageDigest),
+	}
+	return result, nil
 }
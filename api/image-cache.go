@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// コンテナイメージ一覧をキャッシュする時間のデフォルト
+const DefaultImageCacheTtl = 30 * time.Second
+
+// リポジトリごとのコンテナイメージ一覧のキャッシュ（nil の場合はキャッシュしない）
+type ImageCache struct {
+	ttl     time.Duration
+	lock    sync.Mutex
+	entries map[string]imageCacheEntry
+	// 無効化するたびに増やす（無効化前に始めた取得の結果はキャッシュしない）
+	generations map[string]uint64
+	// 同時に取得する場合は ECR の呼び出しを 1 回にまとめる
+	group singleflight.Group
+}
+
+type imageCacheEntry struct {
+	images    []Image
+	fetchedAt time.Time
+}
+
+// キャッシュから取得したコンテナイメージ一覧
+type CachedImages struct {
+	Images []Image
+	// キャッシュの有効期間内だった場合は true
+	Hit bool
+	// キャッシュしてからの経過時間（ECR から取得した場合は 0）
+	Age time.Duration
+}
+
+// ttl が 0 以下の場合はキャッシュしない（nil を返す）
+func NewImageCache(ttl time.Duration) *ImageCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &ImageCache{
+		ttl:         ttl,
+		entries:     make(map[string]imageCacheEntry),
+		generations: make(map[string]uint64),
+	}
+}
+
+// コンテナイメージ一覧の取得（キャッシュの有効期間を過ぎている場合は fetch で取得してキャッシュ）
+func (c *ImageCache) Get(ctx context.Context, repository string, fetch func(ctx context.Context) ([]Image, error)) (CachedImages, error) {
+	if c == nil {
+		// キャッシュする場合と同じく、呼び出し元が既に接続を切っていれば取得しない
+		if err := ctx.Err(); err != nil {
+			return CachedImages{}, err
+		}
+		images, err := fetch(ctx)
+		return CachedImages{Images: images}, err
+	}
+	c.lock.Lock()
+	entry, ok := c.entries[repository]
+	generation := c.generations[repository]
+	c.lock.Unlock()
+	if ok {
+		age := time.Since(entry.fetchedAt)
+		if age < c.ttl {
+			return CachedImages{
+				Images: entry.images,
+				Hit:    true,
+				Age:    age,
+			}, nil
+		}
+	}
+
+	// 無効化後のリクエストが無効化前に始めた取得の結果を受け取らないよう、世代ごとにまとめる
+	// （取得は他の呼び出し元と共有するため、最初の呼び出し元が接続を切っても止めない）
+	key := fmt.Sprintf("%s\x00%d", repository, generation)
+	fetchCtx := detachContext(ctx)
+	resultChan := c.group.DoChan(key, func() (interface{}, error) {
+		images, err := fetch(fetchCtx)
+		if err != nil {
+			return nil, err
+		}
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		if c.generations[repository] == generation {
+			c.entries[repository] = imageCacheEntry{
+				images:    images,
+				fetchedAt: time.Now(),
+			}
+		}
+		return images, nil
+	})
+	select {
+	case <-ctx.Done():
+		return CachedImages{}, ctx.Err()
+	case result := <-resultChan:
+		if result.Err != nil {
+			return CachedImages{}, result.Err
+		}
+		return CachedImages{
+			Images: result.Val.([]Image),
+		}, nil
+	}
+}
+
+// リポジトリのキャッシュを無効化（タグの付け替え・削除後に呼ぶ）
+func (c *ImageCache) Invalidate(repository string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, repository)
+	c.generations[repository]++
+}
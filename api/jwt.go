@@ -0,0 +1,226 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.uber.org/zap"
+)
+
+// 設定ファイル（JWT の検証）
+type JwtConfig struct {
+	// 検証に使う公開鍵（JWKS）のファイル
+	JwksFile string `yaml:"jwks_file"`
+	// iss クレーム（省略時は確認しない）
+	Issuer string `yaml:"issuer"`
+	// aud クレーム（省略時は確認しない）
+	Audience string `yaml:"audience"`
+	// グループを取得するクレーム（省略時は groups）
+	GroupsClaim string `yaml:"groups_claim"`
+}
+
+// 受け付ける署名アルゴリズム（公開鍵による検証のみ）
+var jwtValidMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// JWKS の鍵
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWT の検証（JWKS ファイルは更新されると読み直す）
+type JwtVerifier struct {
+	mu      sync.Mutex
+	cfg     JwtConfig
+	modTime time.Time
+	keys    map[string]interface{}
+}
+
+func NewJwtVerifier(cfg JwtConfig) (*JwtVerifier, error) {
+	if cfg.JwksFile == "" {
+		return nil, errors.New("JWKS ファイルの指定がありません")
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	verifier := &JwtVerifier{
+		cfg: cfg,
+	}
+	err := verifier.reload()
+	if err != nil {
+		return nil, err
+	}
+	return verifier, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// JWKS の鍵を公開鍵に変換
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("曲線（%s）には対応していません", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("鍵の種類（%s）には対応していません", k.Kty)
+}
+
+// JWKS ファイルが更新されていれば読み直す
+func (v *JwtVerifier) reload() error {
+	path := v.cfg.JwksFile
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("JWKS ファイル（%s）の読み込みに失敗しました : %s", path, err)
+	}
+	if info.ModTime().Equal(v.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("JWKS ファイル（%s）の読み込みに失敗しました : %s", path, err)
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err = json.Unmarshal(data, &jwks)
+	if err != nil {
+		return fmt.Errorf("JWKS ファイル（%s）の形式が誤っています : %s", path, err)
+	}
+	keys := make(map[string]interface{})
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return fmt.Errorf("JWKS ファイル（%s）の鍵（%s）の形式が誤っています : %s", path, k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("JWKS ファイル（%s）に署名検証用の鍵がありません", path)
+	}
+	v.keys = keys
+	v.modTime = info.ModTime()
+	return nil
+}
+
+// トークンの kid に対応する公開鍵（kid がなく鍵が 1 つだけの場合はその鍵）
+func (v *JwtVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	// 読み直しに失敗した場合は読み込み済みの鍵で検証
+	err := v.reload()
+	if err != nil {
+		zap.L().Warn("failed to reload JWKS file", zap.String("path", v.cfg.JwksFile), zap.Error(err))
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, nil
+		}
+	}
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("鍵（%s）が JWKS にありません", kid)
+	}
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("鍵（%s）の種類が署名アルゴリズムと一致しません", kid)
+		}
+	case *jwt.SigningMethodECDSA:
+		if _, ok := key.(*ecdsa.PublicKey); !ok {
+			return nil, fmt.Errorf("鍵（%s）の種類が署名アルゴリズムと一致しません", kid)
+		}
+	}
+	return key, nil
+}
+
+// JWT を検証して呼び出し元を取得（exp・sub は必須）
+func (v *JwtVerifier) Verify(tokenString string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, v.keyFunc, jwt.WithValidMethods(jwtValidMethods))
+	if err != nil {
+		return nil, fmt.Errorf("%w : JWT の検証に失敗しました : %s", ErrUnauthorized, err)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w : JWT に exp がありません", ErrUnauthorized)
+	}
+	if v.cfg.Issuer != "" && !claims.VerifyIssuer(v.cfg.Issuer, true) {
+		return nil, fmt.Errorf("%w : JWT の iss が誤っています", ErrUnauthorized)
+	}
+	if v.cfg.Audience != "" && !claims.VerifyAudience(v.cfg.Audience, true) {
+		return nil, fmt.Errorf("%w : JWT の aud が誤っています", ErrUnauthorized)
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w : JWT に sub がありません", ErrUnauthorized)
+	}
+	var groups []string
+	switch value := claims[v.cfg.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range value {
+			if s, ok := group.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	case string:
+		groups = append(groups, value)
+	}
+	return &Principal{
+		Subject: subject,
+		Groups:  groups,
+		Method:  AuthMethodJwt,
+	}, nil
+}
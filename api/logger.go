@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// リクエスト ID のヘッダー
+const RequestIdHeader = "X-Request-Id"
+
+// gin.Context に保存するリクエスト ID のキー
+const requestIdKey = "requestId"
+
+// context.Context に保存するロガーのキー
+type loggerContextKey struct{}
+
+// 呼び出し元から受け取るリクエスト ID の形式（ログに混ぜられない文字を含む場合は生成し直す）
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// アクセスログを debug で出力する死活監視のルート
+var healthCheckRoutes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// ロガーの生成（format は json または console、level は debug・info・warn・error）
+func NewLogger(format string, level string) (*zap.Logger, error) {
+	var zapLevel zapcore.Level
+	err := zapLevel.UnmarshalText([]byte(level))
+	if err != nil {
+		return nil, fmt.Errorf("ログレベル（%s）は debug・info・warn・error のいずれかを指定してください", level)
+	}
+	var cfg zap.Config
+	switch format {
+	case "json":
+		cfg = zap.NewProductionConfig()
+	case "console":
+		cfg = zap.NewDevelopmentConfig()
+	default:
+		return nil, fmt.Errorf("ログの形式（%s）は json または console を指定してください", format)
+	}
+	cfg.Level = zap.NewAtomicLevelAt(zapLevel)
+	cfg.Sampling = nil
+	cfg.EncoderConfig.TimeKey = "time"
+	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	return cfg.Build()
+}
+
+// context にロガーを設定
+func ContextWithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// context のロガー（設定されていない場合は zap.L()）
+func LoggerFromContext(ctx context.Context) *zap.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return zap.L()
+}
+
+// リクエスト ID（RequestLogger を通っていない場合は空）
+func RequestId(c *gin.Context) string {
+	return c.GetString(requestIdKey)
+}
+
+// ECR の呼び出しなどに渡す context（リクエストのロガー・期限を引き継ぎ、呼び出し元が接続を切ると取り消される）
+func requestContext(c *gin.Context) context.Context {
+	return c.Request.Context()
+}
+
+// リクエスト ID の付与とアクセスログの出力（呼び出し元が X-Request-Id を指定しない場合は生成する）
+func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestId := c.GetHeader(RequestIdHeader)
+		if !requestIdPattern.MatchString(requestId) {
+			requestId = uuid.NewString()
+		}
+		c.Set(requestIdKey, requestId)
+		c.Header(RequestIdHeader, requestId)
+		requestLogger := logger.With(zap.String("request_id", requestId))
+		c.Request = c.Request.WithContext(ContextWithLogger(c.Request.Context(), requestLogger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("route", c.FullPath()),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("client_ip", c.ClientIP()),
+		}
+		if principal, ok := PrincipalFromContext(c); ok {
+			fields = append(fields, zap.String("subject", principal.Subject))
+		}
+		switch {
+		case healthCheckRoutes[c.FullPath()]:
+			// ロードバランサーなどから定期的に呼ばれるため debug（/readyz の結果が変わった場合は ReadinessChecker が出力）
+			requestLogger.Debug("request", fields...)
+		case status >= http.StatusInternalServerError:
+			requestLogger.Error("request", fields...)
+		case status >= http.StatusBadRequest:
+			requestLogger.Warn("request", fields...)
+		default:
+			requestLogger.Info("request", fields...)
+		}
+	}
+}
+
+// panic をログに出力して 500 を返す（RequestLogger の後に設定する）
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				LoggerFromContext(c.Request.Context()).Error("panic recovered", zap.Any("panic", r), zap.Stack("stack"))
+				sendError(c, http.StatusInternalServerError, "内部エラーが発生しました")
+				c.Abort()
+			}
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// メトリクス名の接頭辞
+const metricsNamespace = "set_release_tag"
+
+// OpenAPI の操作に該当しないリクエストの操作名
+const unknownOperation = "unknown"
+
+// Prometheus のメトリクス
+type Metrics struct {
+	registry            *prometheus.Registry
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	ecrRequests         *prometheus.CounterVec
+	ecrErrors           *prometheus.CounterVec
+	ecrRequestDuration  *prometheus.HistogramVec
+	releases            *prometheus.CounterVec
+	lastRelease         *prometheus.GaugeVec
+}
+
+// メトリクスの生成（プロセス・Go ランタイムのメトリクスを含む）
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by OpenAPI operation and status code.",
+		}, []string{"operation", "code"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by OpenAPI operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		ecrRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "ecr_requests_total",
+			Help:      "Number of ECR API calls by method.",
+		}, []string{"method"}),
+		ecrErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "ecr_errors_total",
+			Help:      "Number of failed ECR API calls by method and error code.",
+		}, []string{"method", "code"}),
+		ecrRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "ecr_request_duration_seconds",
+			Help:      "ECR API call latency by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		releases: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "releases_total",
+			Help:      "Number of releases, rollbacks and tag deletions by repository and result.",
+		}, []string{"repository", "operation", "result"}),
+		lastRelease: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "last_successful_release_timestamp_seconds",
+			Help:      "Unix time of the last successful release by repository.",
+		}, []string{"repository"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.ecrRequests,
+		m.ecrErrors,
+		m.ecrRequestDuration,
+		m.releases,
+		m.lastRelease,
+	)
+	return m
+}
+
+// Prometheus のテキスト形式でメトリクスを返す Handler
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// メトリクスの取得
+func (s *SetReleaseTag) GetMetrics(c *gin.Context) {
+	if s.Metrics == nil {
+		sendError(c, http.StatusNotFound, "メトリクスは記録していません")
+		return
+	}
+	s.Metrics.Handler().ServeHTTP(c.Writer, c.Request)
+}
+
+// ルート（「メソッド パス」、パスは Gin の形式）と OpenAPI の操作名（ハンドラー名）の対応
+func OperationNames(swagger *openapi3.T) map[string]string {
+	operations := make(map[string]string)
+	for path, item := range swagger.Paths {
+		ginPath := strings.NewReplacer("{", ":", "}", "").Replace(path)
+		for method, operation := range item.Operations() {
+			name := operation.OperationID
+			if name != "" {
+				name = strings.ToUpper(name[:1]) + name[1:]
+			}
+			operations[method+" "+ginPath] = name
+		}
+	}
+	return operations
+}
+
+// HTTP リクエストの件数・処理時間を OpenAPI の操作ごとに記録する Middleware
+func (m *Metrics) Middleware(operations map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		operation, ok := operations[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			operation = unknownOperation
+		}
+		m.httpRequests.WithLabelValues(operation, strconv.Itoa(c.Writer.Status())).Inc()
+		m.httpRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	}
+}
+
+// リリース・ロールバック・タグ削除の結果を記録（失敗以外は最終リリース日時を更新）
+func (m *Metrics) observeRelease(record Release) {
+	if m == nil {
+		return
+	}
+	m.releases.WithLabelValues(record.Repository, string(record.Operation), string(record.Result)).Inc()
+	if record.Operation == ReleaseOperationRelease && record.Result != Failure {
+		m.lastRelease.WithLabelValues(record.Repository).Set(float64(record.ReleasedAt.Unix()))
+	}
+}
+
+// ECR API の呼び出しを記録
+func (m *Metrics) observeECR(method string, duration time.Duration, code string) {
+	m.ecrRequests.WithLabelValues(method).Inc()
+	m.ecrRequestDuration.WithLabelValues(method).Observe(duration.Seconds())
+	if code != "" {
+		m.ecrErrors.WithLabelValues(method, code).Inc()
+	}
+}
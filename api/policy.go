@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 操作の権限
+type Permission string
+
+const (
+	// イメージ一覧・リポジトリ情報・リリース履歴の取得
+	PermissionList Permission = "list"
+	// リリースタグの設定（dry run を含む）
+	PermissionRelease Permission = "release"
+	// リリースタグのロールバック
+	PermissionRollback Permission = "rollback"
+	// タグの削除
+	PermissionDeleteTag Permission = "delete_tag"
+	// リリース申請の承認・却下
+	PermissionApprove Permission = "approve"
+)
+
+// すべてのリポジトリを対象にする場合のリポジトリ名
+const PolicyAllRepositories = "*"
+
+// 設定ファイル（権限定義。subjects または groups に一致する呼び出し元に permissions を許可）
+type PolicyConfig struct {
+	// 対象リポジトリ（* はすべてのリポジトリ）
+	Repositories []string     `yaml:"repositories"`
+	Subjects     []string     `yaml:"subjects"`
+	Groups       []string     `yaml:"groups"`
+	Permissions  []Permission `yaml:"permissions"`
+}
+
+// 権限定義
+// （リポジトリ名を明示した定義があるリポジトリでは、* の定義を使わずに明示した定義のみで判定する）
+type Policy struct {
+	rules []PolicyConfig
+	// リポジトリ名を明示した定義があるリポジトリ
+	explicit map[string]bool
+}
+
+// 権限定義の生成（定義がなければ nil を返し、すべての操作を許可する）
+func NewPolicy(configs []PolicyConfig, repositories *RepositoryRegistry) (*Policy, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	errs := validatePolicies(configs, func(name string) bool {
+		_, ok := repositories.Get(name)
+		return ok
+	})
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	policy := &Policy{
+		rules:    configs,
+		explicit: make(map[string]bool),
+	}
+	for _, v := range configs {
+		for _, name := range v.Repositories {
+			if name != PolicyAllRepositories {
+				policy.explicit[name] = true
+			}
+		}
+	}
+	return policy, nil
+}
+
+// 権限定義の確認（defined はリポジトリが定義されているか。見つかったすべての誤りを返す）
+func validatePolicies(configs []PolicyConfig, defined func(name string) bool) []error {
+	var errs []error
+	for i, v := range configs {
+		if len(v.Repositories) == 0 {
+			errs = append(errs, fmt.Errorf("権限定義（%d 番目）の repositories の指定がありません", i+1))
+		}
+		if len(v.Subjects) == 0 && len(v.Groups) == 0 {
+			errs = append(errs, fmt.Errorf("権限定義（%d 番目）の subjects または groups の指定がありません", i+1))
+		}
+		if len(v.Permissions) == 0 {
+			errs = append(errs, fmt.Errorf("権限定義（%d 番目）の permissions の指定がありません", i+1))
+		}
+		for _, permission := range v.Permissions {
+			switch permission {
+			case PermissionList, PermissionRelease, PermissionRollback, PermissionDeleteTag, PermissionApprove:
+			default:
+				errs = append(errs, fmt.Errorf("権限定義（%d 番目）の権限（%s）は %s・%s・%s・%s・%s のいずれかを指定してください", i+1, permission, PermissionList, PermissionRelease, PermissionRollback, PermissionDeleteTag, PermissionApprove))
+			}
+		}
+		for _, name := range v.Repositories {
+			if name != PolicyAllRepositories && !defined(name) {
+				errs = append(errs, fmt.Errorf("権限定義（%d 番目）のリポジトリ（%s）は定義されていません", i+1, name))
+			}
+		}
+	}
+	return errs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// 権限定義が呼び出し元・リポジトリに該当するか
+func (r PolicyConfig) matches(principal *Principal, repository string, explicit bool) bool {
+	if explicit {
+		if !contains(r.Repositories, repository) {
+			return false
+		}
+	} else if !contains(r.Repositories, repository) && !contains(r.Repositories, PolicyAllRepositories) {
+		return false
+	}
+	if contains(r.Subjects, principal.Subject) {
+		return true
+	}
+	for _, group := range principal.Groups {
+		if contains(r.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// 権限がない場合のエラー
+var ErrForbidden = errors.New("権限がありません")
+
+// 呼び出し元がリポジトリに対する権限を持つか確認（principal が nil の場合は認証していない呼び出し元）
+func (p *Policy) Authorize(principal *Principal, repository string, permission Permission) error {
+	if p == nil {
+		return nil
+	}
+	if principal == nil {
+		return fmt.Errorf("%w : 認証していない呼び出し元にはリポジトリ（%s）の %s 権限がありません", ErrForbidden, repository, permission)
+	}
+	for _, v := range p.rules {
+		if !v.matches(principal, repository, p.explicit[repository]) {
+			continue
+		}
+		for _, allowed := range v.Permissions {
+			if allowed == permission {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w : %s にはリポジトリ（%s）の %s 権限がありません", ErrForbidden, principal.Subject, repository, permission)
+}
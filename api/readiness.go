@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// リクエストを受け付けられるかの確認結果をキャッシュする時間のデフォルト
+const DefaultReadinessTtl = 10 * time.Second
+
+// 確認 1 回あたりのタイムアウト
+const readinessTimeout = 5 * time.Second
+
+// リポジトリにアクセスできるかの確認（アクセスできない場合は理由をエラーで返す）
+type ReadinessCheck func(ctx context.Context, repository Repository) error
+
+// リクエストを受け付けられるかの確認（結果は ttl の間キャッシュし、同時に呼ばれた場合も確認は 1 回だけ行う）
+type ReadinessChecker struct {
+	repositories *RepositoryRegistry
+	check        ReadinessCheck
+	ttl          time.Duration
+	lock         sync.Mutex
+	result       *Readiness
+	expiresAt    time.Time
+}
+
+func NewReadinessChecker(repositories *RepositoryRegistry, check ReadinessCheck, ttl time.Duration) *ReadinessChecker {
+	return &ReadinessChecker{
+		repositories: repositories,
+		check:        check,
+		ttl:          ttl,
+	}
+}
+
+// すべてのリポジトリにアクセスできるか確認（キャッシュが有効な間は前回の結果を返す）
+func (r *ReadinessChecker) Check(ctx context.Context) Readiness {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := time.Now()
+	if r.result != nil && now.Before(r.expiresAt) {
+		return *r.result
+	}
+
+	// リポジトリごとに並行して確認（結果は他の呼び出し元と共有するため、呼び出し元が接続を切っても止めない）
+	ctx, cancel := context.WithTimeout(detachContext(ctx), readinessTimeout)
+	defer cancel()
+	repositories := r.repositories.List()
+	results := make([]RepositoryReadiness, len(repositories))
+	var wg sync.WaitGroup
+	for i, v := range repositories {
+		wg.Add(1)
+		go func(i int, repository Repository) {
+			defer wg.Done()
+			results[i] = RepositoryReadiness{
+				Name:  repository.Name,
+				Ready: true,
+			}
+			err := r.check(ctx, repository)
+			if err != nil {
+				message := err.Error()
+				results[i].Ready = false
+				results[i].Message = &message
+			}
+		}(i, v)
+	}
+	wg.Wait()
+
+	result := Readiness{
+		Status:       Ready,
+		CheckedAt:    now,
+		Repositories: results,
+	}
+	for _, v := range results {
+		if !v.Ready {
+			result.Status = NotReady
+		}
+	}
+	r.logTransition(ctx, result)
+	r.result = &result
+	r.expiresAt = now.Add(r.ttl)
+	return result
+}
+
+// 確認結果が前回から変わった場合のみログに出力
+func (r *ReadinessChecker) logTransition(ctx context.Context, result Readiness) {
+	if r.result != nil && r.result.Status == result.Status {
+		return
+	}
+	logger := LoggerFromContext(ctx)
+	if result.Status == Ready {
+		logger.Info("ready")
+		return
+	}
+	for _, v := range result.Repositories {
+		if !v.Ready {
+			logger.Warn("not ready", zap.String("repository", v.Name), zap.String("error", *v.Message))
+		}
+	}
+}
+
+// AWS の認証情報を取得でき、リポジトリの情報を取得できるか確認
+func (s *SetReleaseTag) CheckRepositoryAccess(ctx context.Context, repository Repository) error {
+	if credentials, ok := s.EcrClients.(CredentialsChecker); ok {
+		err := credentials.CheckCredentials(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	ecrClient, err := s.ecrClient(repository)
+	if err != nil {
+		return err
+	}
+	_, err = TagMutability(ctx, ecrClient, repository.Uri)
+	return err
+}
+
+// 死活監視（プロセスが動作していれば常に 200）
+func (s *SetReleaseTag) GetHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, Health{
+		Status: Ok,
+	})
+}
+
+// リクエストを受け付けられるかの確認（受け付けられない場合は 503）
+func (s *SetReleaseTag) GetReadyz(c *gin.Context) {
+	result := s.Readiness.Check(requestContext(c))
+	if result.Status != Ready {
+		c.JSON(http.StatusServiceUnavailable, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
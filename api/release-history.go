@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// リリース履歴を保存する bucket
+var releasesBucket = []byte("releases")
+
+// リリース申請を保存する bucket
+var releaseRequestsBucket = []byte("release_requests")
+
+// 予約リリースを保存する bucket
+var scheduledReleasesBucket = []byte("scheduled_releases")
+
+// リリース履歴の保存先（bbolt）
+type ReleaseStore struct {
+	db *bolt.DB
+}
+
+// リリース履歴の保存先を開く（ファイルがなければ作成）
+func OpenReleaseStore(path string) (*ReleaseStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("リリース履歴ファイル（%s）を開けませんでした : %s", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, v := range [][]byte{releasesBucket, releaseRequestsBucket, scheduledReleasesBucket} {
+			_, err := tx.CreateBucketIfNotExists(v)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("リリース履歴ファイル（%s）の初期化に失敗しました : %s", path, err)
+	}
+	return &ReleaseStore{db: db}, nil
+}
+
+func (s *ReleaseStore) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// ID をキーに変換（ビッグエンディアンでキー順 = ID 順にする）
+func releaseKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+// リリース履歴を追加（ID は採番して record に設定）
+func (s *ReleaseStore) Add(record *Release) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(releasesBucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		record.Id = int64(id)
+		value, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(releaseKey(id), value)
+	})
+	if err != nil {
+		return fmt.Errorf("リリース履歴の保存に失敗しました : %s", err)
+	}
+	return nil
+}
+
+// リリース履歴を新しい順に取得
+// （repository が空なら全リポジトリ、cursor が 1 以上ならその ID より古いもの。続きがあれば次の cursor を返す）
+func (s *ReleaseStore) List(repository string, cursor int64, limit int) ([]Release, int64, error) {
+	releases := []Release{}
+	var nextCursor int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(releasesBucket).Cursor()
+		var k, v []byte
+		if cursor > 0 {
+			k, _ = c.Seek(releaseKey(uint64(cursor)))
+			if k == nil {
+				// cursor が最新の ID より大きい場合
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		} else {
+			k, v = c.Last()
+		}
+		for ; k != nil; k, v = c.Prev() {
+			var record Release
+			err := json.Unmarshal(v, &record)
+			if err != nil {
+				return err
+			}
+			if repository != "" && record.Repository != repository {
+				continue
+			}
+			if len(releases) == limit {
+				nextCursor = releases[len(releases)-1].Id
+				break
+			}
+			releases = append(releases, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("リリース履歴の取得に失敗しました : %s", err)
+	}
+	return releases, nextCursor, nil
+}
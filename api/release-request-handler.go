@@ -0,0 +1,323 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// リリース申請を使えるか確認（リリース履歴を記録していない場合は 503 を返却）
+func (s *SetReleaseTag) releaseRequestsAvailable(c *gin.Context) bool {
+	if s.Releases == nil {
+		sendError(c, http.StatusServiceUnavailable, "リリース履歴を記録していないため、リリース申請は使えません")
+		return false
+	}
+	return true
+}
+
+// 申請・承認・却下する利用者（認証していない場合は 401 を返却。IP アドレスは X-Forwarded-For で偽装できるため申請者・承認者の区別に使わない）
+func requestPrincipal(c *gin.Context) (string, bool) {
+	principal, ok := PrincipalFromContext(c)
+	if !ok {
+		sendError(c, http.StatusUnauthorized, "リリース申請の申請・承認・却下には認証が必要です")
+		return "", false
+	}
+	return principal.Subject, true
+}
+
+// リリース申請と対象リポジトリを取得（存在しない場合は 404、権限がない場合は 403 を返却）
+func (s *SetReleaseTag) findReleaseRequest(c *gin.Context, id int64, permission Permission) (*ReleaseRequest, Repository, bool) {
+	request, err := s.Releases.GetReleaseRequest(id)
+	if errors.Is(err, ErrReleaseRequestNotFound) {
+		sendError(c, http.StatusNotFound, fmt.Sprintf("%s", err))
+		return nil, Repository{}, false
+	}
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return nil, Repository{}, false
+	}
+	repository, ok := s.findRepository(c, request.Repository)
+	if !ok || !s.authorize(c, repository, permission) {
+		return nil, Repository{}, false
+	}
+	return request, repository, true
+}
+
+// 申請で指定したタグまたはダイジェストをイメージの識別子に変換
+func releaseRequestImageId(request *ReleaseRequest) types.ImageIdentifier {
+	if strings.HasPrefix(request.Source, "sha256:") {
+		return types.ImageIdentifier{ImageDigest: aws.String(request.Source)}
+	}
+	return types.ImageIdentifier{ImageTag: aws.String(request.Source)}
+}
+
+// リリース申請一覧の取得
+func (s *SetReleaseTag) GetReleaseRequests(c *gin.Context, params GetReleaseRequestsParams) {
+	if !s.releaseRequestsAvailable(c) {
+		return
+	}
+	var repositoryName string
+	if params.Repository != nil {
+		repository, ok := s.findRepository(c, *params.Repository)
+		if !ok || !s.authorize(c, repository, PermissionList) {
+			return
+		}
+		repositoryName = repository.Name
+	} else {
+		// 全リポジトリの申請はすべてのリポジトリの list 権限が必要
+		for _, v := range s.Repositories.List() {
+			if !s.authorize(c, v, PermissionList) {
+				return
+			}
+		}
+	}
+	var status ReleaseRequestStatus
+	if params.Status != nil {
+		status = ReleaseRequestStatus(*params.Status)
+	}
+	requests, err := s.Releases.ListReleaseRequests(repositoryName, status)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	c.JSON(http.StatusOK, ReleaseRequestList{
+		ReleaseRequests: requests,
+	})
+}
+
+// リリース申請
+func (s *SetReleaseTag) PostReleaseRequests(c *gin.Context) {
+	if !s.releaseRequestsAvailable(c) {
+		return
+	}
+	requestedBy, ok := requestPrincipal(c)
+	if !ok {
+		return
+	}
+	var input ReleaseRequestInput
+	err := c.Bind(&input)
+	if err != nil {
+		sendError(c, http.StatusBadRequest, fmt.Sprintf("パラメーターの形式が誤っています : %s", err))
+		return
+	}
+	selected, err := selectedImageId(ImageTag{Tag: input.Tag, Digest: input.Digest})
+	if err != nil {
+		sendError(c, http.StatusBadRequest, fmt.Sprintf("パラメーターの形式が誤っています : %s", err))
+		return
+	}
+	repository := s.Repositories.Default()
+	if input.Repository != nil {
+		repository, ok = s.findRepository(c, *input.Repository)
+		if !ok {
+			return
+		}
+	}
+	if !s.authorize(c, repository, PermissionRelease) {
+		return
+	}
+	if input.ScheduledAt != nil && (!s.schedulingAvailable(c) || !validScheduledAt(c, *input.ScheduledAt)) {
+		return
+	}
+
+	// 承認時に同じイメージか確認するため、申請時のダイジェストを記録
+	ecrClient, err := s.ecrClient(repository)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	digest, err := ResolveDigest(requestContext(c), ecrClient, repository.Uri, selected)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("リリース申請が失敗しました : %s", err), err)
+		return
+	}
+	now := time.Now()
+	request := ReleaseRequest{
+		Repository:  repository.Name,
+		Source:      imageIdString(selected),
+		Digest:      digest,
+		Status:      ReleaseRequestStatusPending,
+		RequestedBy: requestedBy,
+		RequestedAt: now,
+		ExpiresAt:   now.Add(s.ApprovalTtl),
+		ScheduledAt: input.ScheduledAt,
+	}
+	err = s.Releases.AddReleaseRequest(&request)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	c.JSON(http.StatusCreated, request)
+}
+
+// リリース申請の取得
+func (s *SetReleaseTag) GetReleaseRequest(c *gin.Context, id ReleaseRequestId) {
+	if !s.releaseRequestsAvailable(c) {
+		return
+	}
+	request, _, ok := s.findReleaseRequest(c, id, PermissionList)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+// 承認待ちでない申請の承認・却下（409 を返却）
+func sendNotPending(c *gin.Context, request *ReleaseRequest) {
+	sendError(c, http.StatusConflict, fmt.Sprintf("リリース申請（%d）は承認待ちではありません（%s）", request.Id, request.Status))
+}
+
+// リリース申請の結果を保存（保存に失敗してもリリース自体の結果は変えない）
+func (s *SetReleaseTag) decideReleaseRequest(ctx context.Context, request *ReleaseRequest, status ReleaseRequestStatus, decidedBy string, tags []string, err error) {
+	now := time.Now()
+	request.Status = status
+	request.DecidedBy = &decidedBy
+	request.DecidedAt = &now
+	if tags != nil {
+		request.Tags = &tags
+	}
+	if err != nil {
+		message := err.Error()
+		request.Message = &message
+	}
+	updateErr := s.Releases.UpdateReleaseRequest(request.Id, func(r *ReleaseRequest) error {
+		*r = *request
+		return nil
+	})
+	if updateErr != nil {
+		LoggerFromContext(ctx).Error("failed to save release request", zap.Int64("release_request_id", request.Id), zap.Error(updateErr))
+	}
+}
+
+// リリース申請の承認（申請者以外が承認した場合のみリリースタグを付け替え）
+func (s *SetReleaseTag) PostReleaseRequestApprove(c *gin.Context, id ReleaseRequestId) {
+	if !s.releaseRequestsAvailable(c) {
+		return
+	}
+	approver, ok := requestPrincipal(c)
+	if !ok {
+		return
+	}
+	s.approvalLock.Lock()
+	defer s.approvalLock.Unlock()
+	request, repository, ok := s.findReleaseRequest(c, id, PermissionApprove)
+	if !ok {
+		return
+	}
+	if request.Status != ReleaseRequestStatusPending {
+		sendNotPending(c, request)
+		return
+	}
+	if approver == request.RequestedBy {
+		sendError(c, http.StatusForbidden, fmt.Sprintf("リリース申請（%d）は申請者（%s）以外が承認してください", request.Id, request.RequestedBy))
+		return
+	}
+
+	ecrClient, err := s.ecrClient(repository)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	s.approveReleaseRequest(c, ecrClient, repository, request, approver)
+}
+
+// 申請時と同じイメージか確認してリリースタグを付け替え
+func (s *SetReleaseTag) approveReleaseRequest(c *gin.Context, ecrClient ECRAPI, repository Repository, request *ReleaseRequest, approver string) {
+	// 申請後に対象のタグが別のイメージに付け替えられていないか確認
+	digest, err := ResolveDigest(requestContext(c), ecrClient, repository.Uri, releaseRequestImageId(request))
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("リリース申請の承認が失敗しました : %s", err), err)
+		return
+	}
+	if digest != request.Digest {
+		err = fmt.Errorf("申請後に対象のイメージ（%s）のダイジェストが変わりました（申請時 : %s、現在 : %s）", request.Source, request.Digest, digest)
+		s.decideReleaseRequest(requestContext(c), request, ReleaseRequestStatusFailed, approver, nil, err)
+		sendError(c, http.StatusConflict, fmt.Sprintf("リリース申請の承認が失敗しました : %s", err))
+		return
+	}
+
+	// 申請時のダイジェストでリリース
+	if request.ScheduledAt != nil && request.ScheduledAt.After(time.Now()) {
+		// 実行予定日時が未来の場合は承認時に予約リリースを作成（過ぎている場合はすぐにリリース）
+		// 付与するタグを生成できることだけ確認し、実行時に改めて生成する
+		if !s.schedulingAvailable(c) {
+			return
+		}
+		_, err = s.releaseTagNames(requestContext(c), ecrClient, repository, request.Source)
+		if err != nil {
+			sendReleaseError(c, "リリース申請の承認", err)
+			return
+		}
+		requestId := request.Id
+		job, err := s.addScheduledRelease(repository, request.Source, request.Digest, *request.ScheduledAt, approver, &requestId)
+		if err != nil {
+			sendServerError(c, fmt.Sprintf("%s", err), err)
+			return
+		}
+		request.ScheduledReleaseId = &job.Id
+		s.decideReleaseRequest(requestContext(c), request, ReleaseRequestStatusApproved, approver, nil, nil)
+		c.Header("X-Release-Status", "scheduled")
+		c.JSON(http.StatusOK, request)
+		return
+	}
+	requestId := request.Id
+	tagResult, err := s.applyReleaseTags(requestContext(c), ecrClient, repository, types.ImageIdentifier{
+		ImageDigest: aws.String(request.Digest),
+	}, Release{
+		Operation:        ReleaseOperationRelease,
+		Repository:       repository.Name,
+		SourceTag:        request.Source,
+		Caller:           approver,
+		ReleaseRequestId: &requestId,
+	})
+	var appliedTags []string
+	if tagResult != nil {
+		appliedTags = tagResult.AppliedTags()
+	}
+	if err != nil {
+		s.decideReleaseRequest(requestContext(c), request, ReleaseRequestStatusFailed, approver, appliedTags, err)
+		sendReleaseError(c, "リリース申請の承認", err)
+		return
+	}
+	s.decideReleaseRequest(requestContext(c), request, ReleaseRequestStatusApproved, approver, appliedTags, nil)
+	if tagResult.Unchanged {
+		c.Header("X-Release-Status", "unchanged")
+	} else {
+		c.Header("X-Release-Status", "updated")
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+// リリース申請の却下（申請者自身は release 権限で取り下げ可、それ以外は approve 権限が必要）
+func (s *SetReleaseTag) PostReleaseRequestReject(c *gin.Context, id ReleaseRequestId) {
+	if !s.releaseRequestsAvailable(c) {
+		return
+	}
+	rejecter, ok := requestPrincipal(c)
+	if !ok {
+		return
+	}
+	s.approvalLock.Lock()
+	defer s.approvalLock.Unlock()
+	permission := PermissionApprove
+	if request, err := s.Releases.GetReleaseRequest(id); err == nil && request.RequestedBy == rejecter {
+		permission = PermissionRelease
+	}
+	request, _, ok := s.findReleaseRequest(c, id, permission)
+	if !ok {
+		return
+	}
+	if request.Status != ReleaseRequestStatusPending {
+		sendNotPending(c, request)
+		return
+	}
+	s.decideReleaseRequest(requestContext(c), request, ReleaseRequestStatusRejected, rejecter, nil, nil)
+	c.JSON(http.StatusOK, request)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// リリース申請が存在しない場合のエラー
+var ErrReleaseRequestNotFound = errors.New("リリース申請が存在しません")
+
+// リリース申請を追加（ID は採番して request に設定）
+func (s *ReleaseStore) AddReleaseRequest(request *ReleaseRequest) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(releaseRequestsBucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		request.Id = int64(id)
+		value, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(releaseKey(id), value)
+	})
+	if err != nil {
+		return fmt.Errorf("リリース申請の保存に失敗しました : %s", err)
+	}
+	return nil
+}
+
+// 承認待ちで有効期限を過ぎたリリース申請を期限切れにする（変更した場合は true）
+func expireReleaseRequest(request *ReleaseRequest, now time.Time) bool {
+	if request.Status != ReleaseRequestStatusPending || now.Before(request.ExpiresAt) {
+		return false
+	}
+	request.Status = ReleaseRequestStatusExpired
+	return true
+}
+
+// リリース申請を取得（有効期限を過ぎた承認待ちの申請は期限切れとして返す）
+func (s *ReleaseStore) GetReleaseRequest(id int64) (*ReleaseRequest, error) {
+	var request *ReleaseRequest
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(releaseRequestsBucket).Get(releaseKey(uint64(id)))
+		if value == nil {
+			return nil
+		}
+		request = &ReleaseRequest{}
+		return json.Unmarshal(value, request)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("リリース申請の取得に失敗しました : %s", err)
+	}
+	if request == nil {
+		return nil, fmt.Errorf("%w : %d", ErrReleaseRequestNotFound, id)
+	}
+	expireReleaseRequest(request, time.Now())
+	return request, nil
+}
+
+// リリース申請を更新（update がエラーを返した場合は更新しない。有効期限を過ぎた承認待ちの申請は期限切れにしてから update を呼ぶ）
+func (s *ReleaseStore) UpdateReleaseRequest(id int64, update func(*ReleaseRequest) error) error {
+	var updateErr error
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(releaseRequestsBucket)
+		key := releaseKey(uint64(id))
+		value := bucket.Get(key)
+		if value == nil {
+			updateErr = fmt.Errorf("%w : %d", ErrReleaseRequestNotFound, id)
+			return nil
+		}
+		var request ReleaseRequest
+		err := json.Unmarshal(value, &request)
+		if err != nil {
+			return err
+		}
+		expired := expireReleaseRequest(&request, time.Now())
+		updateErr = update(&request)
+		if updateErr != nil && !expired {
+			return nil
+		}
+		value, err = json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, value)
+	})
+	if err != nil {
+		return fmt.Errorf("リリース申請の保存に失敗しました : %s", err)
+	}
+	return updateErr
+}
+
+// リリース申請を新しい順に取得（repository・status が空なら絞り込まない）
+func (s *ReleaseStore) ListReleaseRequests(repository string, status ReleaseRequestStatus) ([]ReleaseRequest, error) {
+	requests := []ReleaseRequest{}
+	now := time.Now()
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(releaseRequestsBucket).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var request ReleaseRequest
+			err := json.Unmarshal(v, &request)
+			if err != nil {
+				return err
+			}
+			// 一覧では保存し直さずに期限切れとして返す
+			expireReleaseRequest(&request, now)
+			if repository != "" && request.Repository != repository {
+				continue
+			}
+			if status != "" && request.Status != status {
+				continue
+			}
+			requests = append(requests, request)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("リリース申請の取得に失敗しました : %s", err)
+	}
+	return requests, nil
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ECR リポジトリ URI（<registry_id>.dkr.ecr[-fips].<region>.<domain>/<repository_name>）を分解したもの
+type RepositoryUri struct {
+	// AWS アカウント ID（12 桁）
+	RegistryId string
+	Region     string
+	// aws・aws-cn・aws-us-gov・aws-iso・aws-iso-b
+	Partition string
+	// FIPS エンドポイント（dkr.ecr-fips）の場合は true
+	Fips bool
+	// 名前空間を含むリポジトリ名（team/service/api など）
+	Name string
+}
+
+// リポジトリ URI の形式が誤っている場合のエラー（errors.Is で ErrInvalidRepositoryUri と比較できる）
+var ErrInvalidRepositoryUri = errors.New("リポジトリ URI の形式が誤っています")
+
+type RepositoryUriError struct {
+	Uri    string
+	Reason string
+}
+
+func (e *RepositoryUriError) Error() string {
+	return fmt.Sprintf("リポジトリ URI（%s）の形式が誤っています : %s", e.Uri, e.Reason)
+}
+
+func (e *RepositoryUriError) Unwrap() error {
+	return ErrInvalidRepositoryUri
+}
+
+// ドメインとパーティションの対応
+var repositoryUriDomains = map[string]string{
+	"amazonaws.com":    "aws",
+	"amazonaws.com.cn": "aws-cn",
+	"c2s.ic.gov":       "aws-iso",
+	"sc2s.sgov.gov":    "aws-iso-b",
+}
+
+var (
+	registryIdPattern = regexp.MustCompile(`^[0-9]{12}$`)
+	regionPattern     = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+	// ECR のリポジトリ名の規則（小文字英数字を . _ - / で区切る）
+	repositoryNamePattern = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*(?:/[a-z0-9]+(?:[._-][a-z0-9]+)*)*$`)
+)
+
+// ECR リポジトリ名の長さの範囲（API 仕様）
+const (
+	repositoryNameMinLength = 2
+	repositoryNameMaxLength = 256
+)
+
+// リポジトリ URI を分解（形式が誤っている場合は *RepositoryUriError を返す）
+func ParseRepositoryUri(uri string) (RepositoryUri, error) {
+	invalid := func(format string, args ...interface{}) (RepositoryUri, error) {
+		return RepositoryUri{}, &RepositoryUriError{Uri: uri, Reason: fmt.Sprintf(format, args...)}
+	}
+	if strings.Contains(uri, "://") {
+		return invalid("スキーム（https:// など）は付けずに指定してください")
+	}
+	host, name, ok := strings.Cut(uri, "/")
+	if !ok || name == "" {
+		return invalid("リポジトリ名がありません")
+	}
+
+	// ホスト名は大文字・小文字を区別しない
+	labels := strings.Split(strings.ToLower(host), ".")
+	if len(labels) < 6 {
+		return invalid("ホスト名（%s）が ECR のレジストリではありません", host)
+	}
+	if labels[1] != "dkr" || (labels[2] != "ecr" && labels[2] != "ecr-fips") {
+		return invalid("ホスト名（%s）が ECR のレジストリではありません", host)
+	}
+	partition, ok := repositoryUriDomains[strings.Join(labels[4:], ".")]
+	if !ok {
+		return invalid("ホスト名（%s）のドメインが ECR のレジストリではありません", host)
+	}
+	registryId := labels[0]
+	if !registryIdPattern.MatchString(registryId) {
+		return invalid("レジストリ ID（%s）は 12 桁の数字で指定してください", registryId)
+	}
+	region := labels[3]
+	if !regionPattern.MatchString(region) {
+		return invalid("リージョン（%s）の形式が誤っています", region)
+	}
+	if partition == "aws" && strings.HasPrefix(region, "us-gov-") {
+		partition = "aws-us-gov"
+	}
+
+	if strings.ContainsAny(name, ":@") {
+		return invalid("タグ・ダイジェストは付けずに指定してください")
+	}
+	if len(name) < repositoryNameMinLength || len(name) > repositoryNameMaxLength {
+		return invalid("リポジトリ名は %d 〜 %d 文字で指定してください", repositoryNameMinLength, repositoryNameMaxLength)
+	}
+	if !repositoryNamePattern.MatchString(name) {
+		return invalid("リポジトリ名（%s）に使えない文字が含まれています", name)
+	}
+	return RepositoryUri{
+		RegistryId: registryId,
+		Region:     region,
+		Partition:  partition,
+		Fips:       labels[2] == "ecr-fips",
+		Name:       name,
+	}, nil
+}
+
+// レジストリのホスト名
+func (u RepositoryUri) Host() string {
+	service := "ecr"
+	if u.Fips {
+		service = "ecr-fips"
+	}
+	domain := "amazonaws.com"
+	switch u.Partition {
+	case "aws-cn":
+		domain = "amazonaws.com.cn"
+	case "aws-iso":
+		domain = "c2s.ic.gov"
+	case "aws-iso-b":
+		domain = "sc2s.sgov.gov"
+	}
+	return fmt.Sprintf("%s.dkr.%s.%s.%s", u.RegistryId, service, u.Region, domain)
+}
+
+func (u RepositoryUri) String() string {
+	return u.Host() + "/" + u.Name
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// リポジトリ（論理名と ECR リポジトリ URI）
+type Repository struct {
+	Name string
+	Uri  string
+	// リリースにリリース申請の承認が必要か
+	RequireApproval bool
+}
+
+// リポジトリ一覧
+type RepositoryRegistry struct {
+	repositories map[string]Repository
+	defaultName  string
+}
+
+// リポジトリ一覧生成（defaultName が空の場合は先頭のリポジトリをデフォルトとする）
+func NewRepositoryRegistry(repositories []RepositoryConfig, defaultName string) (*RepositoryRegistry, error) {
+	errs := validateRepositories(repositories, defaultName)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	registry := &RepositoryRegistry{
+		repositories: make(map[string]Repository),
+		defaultName:  defaultName,
+	}
+	for _, v := range repositories {
+		registry.repositories[v.Name] = Repository{
+			Name:            v.Name,
+			Uri:             v.Uri,
+			RequireApproval: v.RequireApproval,
+		}
+	}
+	if registry.defaultName == "" {
+		registry.defaultName = repositories[0].Name
+	}
+	return registry, nil
+}
+
+// リポジトリ定義の確認（見つかったすべての誤りを返す）
+func validateRepositories(repositories []RepositoryConfig, defaultName string) []error {
+	if len(repositories) == 0 {
+		return []error{errors.New("リポジトリの指定がありません")}
+	}
+	var errs []error
+	names := make(map[string]bool)
+	for _, v := range repositories {
+		if v.Name == "" {
+			errs = append(errs, fmt.Errorf("リポジトリ（%s）の名前の指定がありません", v.Uri))
+		}
+		if v.Uri == "" {
+			errs = append(errs, fmt.Errorf("リポジトリ（%s）の URI の指定がありません", v.Name))
+		} else if _, err := ParseRepositoryUri(v.Uri); err != nil {
+			errs = append(errs, fmt.Errorf("リポジトリ（%s） : %w", v.Name, err))
+		}
+		if v.Name != "" && names[v.Name] {
+			errs = append(errs, fmt.Errorf("リポジトリ名（%s）が重複しています", v.Name))
+		}
+		names[v.Name] = true
+	}
+	if defaultName != "" && !names[defaultName] {
+		errs = append(errs, fmt.Errorf("デフォルトのリポジトリ（%s）が定義されていません", defaultName))
+	}
+	return errs
+}
+
+// 名前でリポジトリを取得
+func (r *RepositoryRegistry) Get(name string) (Repository, bool) {
+	repository, ok := r.repositories[name]
+	return repository, ok
+}
+
+// デフォルトのリポジトリを取得
+func (r *RepositoryRegistry) Default() Repository {
+	return r.repositories[r.defaultName]
+}
+
+// すべてのリポジトリを名前順に取得
+func (r *RepositoryRegistry) List() []Repository {
+	var repositories []Repository
+	for _, v := range r.repositories {
+		repositories = append(repositories, v)
+	}
+	sort.Slice(repositories, func(i, j int) bool {
+		return repositories[i].Name < repositories[j].Name
+	})
+	return repositories
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// リクエストボディの上限（デフォルト）
+const DefaultMaxBodySize = int64(1 << 20)
+
+// リクエストボディが maxBodySize バイトを超える場合は 413 を返す Middleware（0 以下の場合は制限しない）
+// （Validation でボディを読む前に上限まで読み込んでおく）
+func MaxBodySize(maxBodySize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBodySize <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > maxBodySize {
+			sendBodyTooLarge(c, maxBodySize)
+			return
+		}
+		// 上限を 1 バイトでも超えたかを判定できるよう、上限 + 1 バイトまで読み込む
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodySize+1))
+		if err != nil {
+			// 接続の切断・読み込みのタイムアウトなど（上限超過ではない）
+			if c.Request.Context().Err() != nil {
+				sendServerError(c, "リクエストボディの読み込みを中止しました", err)
+			} else {
+				sendError(c, http.StatusBadRequest, fmt.Sprintf("リクエストボディの読み込みに失敗しました : %s", err))
+			}
+			c.Abort()
+			return
+		}
+		if int64(len(body)) > maxBodySize {
+			sendBodyTooLarge(c, maxBodySize)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+func sendBodyTooLarge(c *gin.Context, maxBodySize int64) {
+	sendError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("リクエストボディが上限（%d バイト）を超えています", maxBodySize))
+	c.Abort()
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ロールバックで戻せるリリース数（リポジトリごと）のデフォルト
+const DefaultRollbackDepth = 5
+
+// リリースタグを付け替えた（外した）記録か（一部のタグの付与に失敗しても、リリースタグを付け替えていれば含める）
+func movedReleaseTag(record Release) bool {
+	switch record.Result {
+	case Success, Unchanged:
+		return true
+	case Failure:
+		return record.Tags != nil && contains(*record.Tags, record.Tag)
+	}
+	return false
+}
+
+// ロールバックで戻す先のダイジェスト（戻せない場合は false）
+// リリース履歴を新しい順にたどり、ロールバック済みのリリース・タグ削除を飛ばして、その前にリリースタグが付いていたイメージを返す。
+// 連続して戻せるのは、リリースタグを付け替えた直近 depth 件まで
+func (s *ReleaseStore) RollbackTarget(repository string, releaseTag string, depth int) (string, bool, error) {
+	var digest string
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(releasesBucket).Cursor()
+		// まだ戻す先として使っていない、ロールバック済みのリリース・タグ削除の数
+		skip := 0
+		// たどったリリース・タグ削除の数
+		moves := 0
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var record Release
+			err := json.Unmarshal(v, &record)
+			if err != nil {
+				return err
+			}
+			if record.Repository != repository || record.Tag != releaseTag || !movedReleaseTag(record) {
+				continue
+			}
+			switch record.Operation {
+			case ReleaseOperationRollback:
+				skip++
+				continue
+			case ReleaseOperationRelease, ReleaseOperationUntag:
+			default:
+				continue
+			}
+			// リリースタグが付いていなかった・付いているイメージが変わらなかった場合は戻す先にならない
+			if record.PreviousDigest == "" || record.PreviousDigest == record.Digest {
+				continue
+			}
+			moves++
+			if moves > depth {
+				return nil
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			digest = record.PreviousDigest
+			found = true
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("リリース履歴の取得に失敗しました : %s", err)
+	}
+	return digest, found, nil
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/gin-gonic/gin"
+)
+
+// 予約リリースを使えるか確認（リリース履歴を記録していない場合は 503 を返却）
+func (s *SetReleaseTag) scheduledReleasesAvailable(c *gin.Context) bool {
+	if s.Releases == nil {
+		sendError(c, http.StatusServiceUnavailable, "リリース履歴を記録していないため、予約リリースは使えません")
+		return false
+	}
+	return true
+}
+
+// 予約リリースを作成できるか確認（リリース履歴を記録していない・予約リリースを実行しない場合は 503 を返却）
+func (s *SetReleaseTag) schedulingAvailable(c *gin.Context) bool {
+	if !s.scheduledReleasesAvailable(c) {
+		return false
+	}
+	if !s.SchedulerEnabled {
+		sendError(c, http.StatusServiceUnavailable, "予約リリースを実行しない設定のため、scheduled_at は指定できません")
+		return false
+	}
+	return true
+}
+
+// 実行予定日時が未来か確認（過去の場合は 400 を返却）
+func validScheduledAt(c *gin.Context, scheduledAt time.Time) bool {
+	if !scheduledAt.After(time.Now()) {
+		sendError(c, http.StatusBadRequest, fmt.Sprintf("実行予定日時（%s）は現在より後の日時を指定してください", scheduledAt.Format(time.RFC3339)))
+		return false
+	}
+	return true
+}
+
+// 予約リリースと対象リポジトリを取得（存在しない場合は 404、権限がない場合は 403 を返却）
+func (s *SetReleaseTag) findScheduledRelease(c *gin.Context, id int64, permission Permission) (*ScheduledRelease, bool) {
+	job, err := s.Releases.GetScheduledRelease(id)
+	if errors.Is(err, ErrScheduledReleaseNotFound) {
+		sendError(c, http.StatusNotFound, fmt.Sprintf("%s", err))
+		return nil, false
+	}
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return nil, false
+	}
+	// 設定ファイルから削除したリポジトリの予約も参照・取り消しできるようにする
+	repository, ok := s.Repositories.Get(job.Repository)
+	if !ok {
+		repository = Repository{Name: job.Repository}
+	}
+	if !s.authorize(c, repository, permission) {
+		return nil, false
+	}
+	return job, true
+}
+
+// 予約リリースを作成
+func (s *SetReleaseTag) addScheduledRelease(repository Repository, source string, digest string, scheduledAt time.Time, createdBy string, releaseRequestId *int64) (*ScheduledRelease, error) {
+	job := ScheduledRelease{
+		Repository:       repository.Name,
+		Source:           source,
+		Digest:           digest,
+		ScheduledAt:      scheduledAt,
+		Status:           ScheduledReleaseStatusPending,
+		CreatedBy:        createdBy,
+		CreatedAt:        time.Now(),
+		ReleaseRequestId: releaseRequestId,
+	}
+	err := s.Releases.AddScheduledRelease(&job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// 予約リリースを作成して 202 を返却（予約時のダイジェストを記録し、実行時はそのイメージをリリースする）
+func (s *SetReleaseTag) scheduleRelease(c *gin.Context, ecrClient ECRAPI, repository Repository, selected types.ImageIdentifier, scheduledAt time.Time) {
+	digest, err := ResolveDigest(requestContext(c), ecrClient, repository.Uri, selected)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("リリースの予約が失敗しました : %s", err), err)
+		return
+	}
+	job, err := s.addScheduledRelease(repository, imageIdString(selected), digest, scheduledAt, caller(c), nil)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	c.Header("X-Release-Status", "scheduled")
+	c.JSON(http.StatusAccepted, job)
+}
+
+// 予約リリース一覧の取得
+func (s *SetReleaseTag) GetScheduledReleases(c *gin.Context, params GetScheduledReleasesParams) {
+	if !s.scheduledReleasesAvailable(c) {
+		return
+	}
+	var repositoryName string
+	if params.Repository != nil {
+		repository, ok := s.findRepository(c, *params.Repository)
+		if !ok || !s.authorize(c, repository, PermissionList) {
+			return
+		}
+		repositoryName = repository.Name
+	} else {
+		// 全リポジトリの予約はすべてのリポジトリの list 権限が必要
+		for _, v := range s.Repositories.List() {
+			if !s.authorize(c, v, PermissionList) {
+				return
+			}
+		}
+	}
+	var status ScheduledReleaseStatus
+	if params.Status != nil {
+		status = ScheduledReleaseStatus(*params.Status)
+	}
+	jobs, err := s.Releases.ListScheduledReleases(repositoryName, status)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	c.JSON(http.StatusOK, ScheduledReleaseList{
+		ScheduledReleases: jobs,
+	})
+}
+
+// 予約リリースの取得
+func (s *SetReleaseTag) GetScheduledRelease(c *gin.Context, id ScheduledReleaseId) {
+	if !s.scheduledReleasesAvailable(c) {
+		return
+	}
+	job, ok := s.findScheduledRelease(c, id, PermissionList)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// 予約リリースの取り消し（release 権限が必要）
+func (s *SetReleaseTag) DeleteScheduledRelease(c *gin.Context, id ScheduledReleaseId) {
+	if !s.scheduledReleasesAvailable(c) {
+		return
+	}
+	if _, ok := s.findScheduledRelease(c, id, PermissionRelease); !ok {
+		return
+	}
+	// 実行と同時に取り消さないよう、実行待ちの場合のみ取り消す
+	var job ScheduledRelease
+	cancelledBy := caller(c)
+	err := s.Releases.UpdateScheduledRelease(id, func(j *ScheduledRelease) error {
+		if j.Status != ScheduledReleaseStatusPending {
+			return errScheduledReleaseNotPending(j)
+		}
+		j.Status = ScheduledReleaseStatusCancelled
+		j.CancelledBy = &cancelledBy
+		job = *j
+		return nil
+	})
+	if errors.Is(err, ErrScheduledReleaseNotFound) {
+		sendError(c, http.StatusNotFound, fmt.Sprintf("%s", err))
+		return
+	}
+	if errors.Is(err, ErrScheduledReleaseNotPending) {
+		sendError(c, http.StatusConflict, fmt.Sprintf("%s", err))
+		return
+	}
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 予約リリースが存在しない場合のエラー
+var ErrScheduledReleaseNotFound = errors.New("予約リリースが存在しません")
+
+// 予約リリースを追加（ID は採番して job に設定）
+func (s *ReleaseStore) AddScheduledRelease(job *ScheduledRelease) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(scheduledReleasesBucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		job.Id = int64(id)
+		value, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(releaseKey(id), value)
+	})
+	if err != nil {
+		return fmt.Errorf("予約リリースの保存に失敗しました : %s", err)
+	}
+	return nil
+}
+
+// 予約リリースを取得
+func (s *ReleaseStore) GetScheduledRelease(id int64) (*ScheduledRelease, error) {
+	var job *ScheduledRelease
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(scheduledReleasesBucket).Get(releaseKey(uint64(id)))
+		if value == nil {
+			return nil
+		}
+		job = &ScheduledRelease{}
+		return json.Unmarshal(value, job)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("予約リリースの取得に失敗しました : %s", err)
+	}
+	if job == nil {
+		return nil, fmt.Errorf("%w : %d", ErrScheduledReleaseNotFound, id)
+	}
+	return job, nil
+}
+
+// 予約リリースを更新（update がエラーを返した場合は更新しない）
+func (s *ReleaseStore) UpdateScheduledRelease(id int64, update func(*ScheduledRelease) error) error {
+	var updateErr error
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(scheduledReleasesBucket)
+		key := releaseKey(uint64(id))
+		value := bucket.Get(key)
+		if value == nil {
+			updateErr = fmt.Errorf("%w : %d", ErrScheduledReleaseNotFound, id)
+			return nil
+		}
+		var job ScheduledRelease
+		err := json.Unmarshal(value, &job)
+		if err != nil {
+			return err
+		}
+		updateErr = update(&job)
+		if updateErr != nil {
+			return nil
+		}
+		value, err = json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, value)
+	})
+	if err != nil {
+		return fmt.Errorf("予約リリースの保存に失敗しました : %s", err)
+	}
+	return updateErr
+}
+
+// 予約リリースを実行予定日時の順に取得（repository・status が空なら絞り込まない）
+func (s *ReleaseStore) ListScheduledReleases(repository string, status ScheduledReleaseStatus) ([]ScheduledRelease, error) {
+	jobs := []ScheduledRelease{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(scheduledReleasesBucket).ForEach(func(k, v []byte) error {
+			var job ScheduledRelease
+			err := json.Unmarshal(v, &job)
+			if err != nil {
+				return err
+			}
+			if repository != "" && job.Repository != repository {
+				return nil
+			}
+			if status != "" && job.Status != status {
+				return nil
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("予約リリースの取得に失敗しました : %s", err)
+	}
+	// 実行予定日時が同じ場合は ID 順（ForEach はキー = ID 順）
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].ScheduledAt.Before(jobs[j].ScheduledAt)
+	})
+	return jobs, nil
+}
+
+// 実行予定日時を過ぎた実行待ちの予約リリースを取得
+func (s *ReleaseStore) DueScheduledReleases(now time.Time) ([]ScheduledRelease, error) {
+	jobs, err := s.ListScheduledReleases("", ScheduledReleaseStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	due := []ScheduledRelease{}
+	for _, v := range jobs {
+		if v.ScheduledAt.After(now) {
+			break
+		}
+		due = append(due, v)
+	}
+	return due, nil
+}
+
+// 実行中のまま停止した予約リリースを実行待ちに戻す（戻した件数を返す）
+// （タグの付け替えは同じイメージに対してやり直しても結果が変わらないため、再実行する）
+func (s *ReleaseStore) ResumeScheduledReleases() (int, error) {
+	count := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(scheduledReleasesBucket)
+		// ForEach の中では bucket を変更できないため、対象を集めてから保存
+		resumed := make(map[string][]byte)
+		err := bucket.ForEach(func(k, v []byte) error {
+			var job ScheduledRelease
+			err := json.Unmarshal(v, &job)
+			if err != nil {
+				return err
+			}
+			if job.Status != ScheduledReleaseStatusRunning {
+				return nil
+			}
+			job.Status = ScheduledReleaseStatusPending
+			value, err := json.Marshal(job)
+			if err != nil {
+				return err
+			}
+			resumed[string(k)] = value
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for k, v := range resumed {
+			err = bucket.Put([]byte(k), v)
+			if err != nil {
+				return err
+			}
+		}
+		count = len(resumed)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("予約リリースの保存に失敗しました : %s", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"go.uber.org/zap"
+)
+
+// 予約リリースを確認する間隔の既定値
+const DefaultScheduleInterval = 30 * time.Second
+
+// 予約リリースの実行（実行予定日時を過ぎた予約を一定間隔で確認して実行する）
+type Scheduler struct {
+	setReleaseTag *SetReleaseTag
+	interval      time.Duration
+	stop          chan struct{}
+	done          chan struct{}
+}
+
+// 予約リリースの実行を開始
+// （予約はリリース履歴ファイルに保存しているため、再起動後も実行する。停止時に実行中だった予約は再実行する）
+func (s *SetReleaseTag) StartScheduler(interval time.Duration) (*Scheduler, error) {
+	if s.Releases == nil {
+		return nil, errors.New("リリース履歴を記録していないため、予約リリースは使えません")
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("予約リリースを確認する間隔（%s）は 0 より大きい値を指定してください", interval)
+	}
+	count, err := s.Releases.ResumeScheduledReleases()
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		zap.L().Info("resuming scheduled releases interrupted by shutdown", zap.Int("count", count))
+	}
+	scheduler := &Scheduler{
+		setReleaseTag: s,
+		interval:      interval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go scheduler.run()
+	return scheduler, nil
+}
+
+func (sc *Scheduler) run() {
+	defer close(sc.done)
+	ticker := time.NewTicker(sc.interval)
+	defer ticker.Stop()
+	for {
+		// 停止中に実行予定日時を過ぎた予約は起動直後に実行
+		sc.setReleaseTag.RunScheduledReleases(context.TODO(), time.Now())
+		select {
+		case <-sc.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// 予約リリースの実行を停止（実行中の予約は完了を待つ。nil の場合は何もしない）
+func (sc *Scheduler) Stop() {
+	if sc == nil {
+		return
+	}
+	close(sc.stop)
+	<-sc.done
+}
+
+// 実行予定日時を過ぎた予約リリースを実行
+func (s *SetReleaseTag) RunScheduledReleases(ctx context.Context, now time.Time) {
+	jobs, err := s.Releases.DueScheduledReleases(now)
+	if err != nil {
+		LoggerFromContext(ctx).Error("failed to load scheduled releases", zap.Error(err))
+		return
+	}
+	for _, job := range jobs {
+		// 予約ごとのログに予約リリースの ID を付ける
+		jobCtx := ContextWithLogger(ctx, LoggerFromContext(ctx).With(zap.Int64("scheduled_release_id", job.Id)))
+		logger := LoggerFromContext(jobCtx)
+		repository, ok := s.Repositories.Get(job.Repository)
+		if !ok {
+			// 設定ファイルからリポジトリを削除した場合は実行しない
+			s.failScheduledRelease(jobCtx, job.Id, fmt.Errorf("リポジトリ（%s）は定義されていません", job.Repository))
+			continue
+		}
+		ecrClient, err := s.ecrClient(repository)
+		if err != nil {
+			// 次回の確認時に再実行
+			logger.Warn("failed to create ECR client for scheduled release", zap.Error(err))
+			continue
+		}
+		result, err := s.executeScheduledReleaseWithTimeout(jobCtx, ecrClient, job.Id)
+		if result == nil {
+			logger.Info("scheduled release skipped", zap.Error(err))
+			continue
+		}
+		if err != nil {
+			logger.Error("scheduled release failed", zap.String("repository", result.Repository), zap.Error(err))
+			continue
+		}
+		logger.Info("scheduled release executed", zap.String("repository", result.Repository), zap.String("source", result.Source))
+	}
+}
+
+// 予約リリース 1 件あたりのタイムアウトを設定して実行
+func (s *SetReleaseTag) executeScheduledReleaseWithTimeout(ctx context.Context, ecrClient ECRAPI, id int64) (*ScheduledRelease, error) {
+	if s.OperationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.OperationTimeout)
+		defer cancel()
+	}
+	return s.ExecuteScheduledRelease(ctx, ecrClient, id)
+}
+
+// 実行待ちでない予約リリースを実行・取り消そうとした場合のエラー
+var ErrScheduledReleaseNotPending = errors.New("予約リリースは実行待ちではありません")
+
+func errScheduledReleaseNotPending(job *ScheduledRelease) error {
+	return fmt.Errorf("%w : %d（%s）", ErrScheduledReleaseNotPending, job.Id, job.Status)
+}
+
+// 予約リリースを実行
+// （予約時のダイジェストのイメージにリリースタグを付け替える。実行待ちでない場合は nil を返す）
+func (s *SetReleaseTag) ExecuteScheduledRelease(ctx context.Context, ecrClient ECRAPI, id int64) (*ScheduledRelease, error) {
+	// 取り消しと同時に実行しないよう、実行中にしてから付け替える
+	var job ScheduledRelease
+	err := s.Releases.UpdateScheduledRelease(id, func(j *ScheduledRelease) error {
+		if j.Status != ScheduledReleaseStatusPending {
+			return errScheduledReleaseNotPending(j)
+		}
+		j.Status = ScheduledReleaseStatusRunning
+		job = *j
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	repository, ok := s.Repositories.Get(job.Repository)
+	if !ok {
+		err = fmt.Errorf("リポジトリ（%s）は定義されていません", job.Repository)
+		s.finishScheduledRelease(ctx, &job, nil, err)
+		return &job, err
+	}
+	if repository.RequireApproval && job.ReleaseRequestId == nil {
+		// 予約後に承認が必要なリポジトリに変わった場合
+		err = fmt.Errorf("リポジトリ（%s）のリリースには承認が必要です", repository.Name)
+		s.finishScheduledRelease(ctx, &job, nil, err)
+		return &job, err
+	}
+	jobId := job.Id
+	tagResult, err := s.applyReleaseTags(ctx, ecrClient, repository, types.ImageIdentifier{
+		ImageDigest: aws.String(job.Digest),
+	}, Release{
+		Operation:          ReleaseOperationRelease,
+		Repository:         repository.Name,
+		SourceTag:          job.Source,
+		Caller:             job.CreatedBy,
+		ReleaseRequestId:   job.ReleaseRequestId,
+		ScheduledReleaseId: &jobId,
+	})
+	var appliedTags []string
+	if tagResult != nil {
+		appliedTags = tagResult.AppliedTags()
+	}
+	s.finishScheduledRelease(ctx, &job, appliedTags, err)
+	return &job, err
+}
+
+// 予約リリースの結果を保存（保存に失敗してもリリース自体の結果は変えない）
+func (s *SetReleaseTag) finishScheduledRelease(ctx context.Context, job *ScheduledRelease, tags []string, err error) {
+	now := time.Now()
+	job.Status = ScheduledReleaseStatusSucceeded
+	job.ExecutedAt = &now
+	if tags != nil {
+		job.Tags = &tags
+	}
+	if err != nil {
+		message := err.Error()
+		job.Status = ScheduledReleaseStatusFailed
+		job.Message = &message
+	}
+	updateErr := s.Releases.UpdateScheduledRelease(job.Id, func(j *ScheduledRelease) error {
+		*j = *job
+		return nil
+	})
+	if updateErr != nil {
+		LoggerFromContext(ctx).Error("failed to save scheduled release", zap.Int64("scheduled_release_id", job.Id), zap.Error(updateErr))
+	}
+}
+
+// 実行せずに予約リリースを失敗にする
+func (s *SetReleaseTag) failScheduledRelease(ctx context.Context, id int64, err error) {
+	var job ScheduledRelease
+	updateErr := s.Releases.UpdateScheduledRelease(id, func(j *ScheduledRelease) error {
+		if j.Status != ScheduledReleaseStatusPending {
+			return errScheduledReleaseNotPending(j)
+		}
+		job = *j
+		return nil
+	})
+	if updateErr != nil {
+		LoggerFromContext(ctx).Info("scheduled release skipped", zap.Error(updateErr))
+		return
+	}
+	LoggerFromContext(ctx).Error("scheduled release failed", zap.String("repository", job.Repository), zap.Error(err))
+	s.finishScheduledRelease(ctx, &job, nil, err)
+}
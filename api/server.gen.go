@@ -8,22 +8,81 @@ import (
 	"compress/gzip"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 	"strings"
 
+	"github.com/deepmap/oapi-codegen/pkg/runtime"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gin-gonic/gin"
 )
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// 死活監視
+	// (GET /healthz)
+	GetHealthz(c *gin.Context)
 	// コンテナイメージ一覧の取得
 	// (GET /images)
 	GetImages(c *gin.Context)
 	// リリースタグセット
 	// (POST /images)
-	PostImages(c *gin.Context)
+	PostImages(c *gin.Context, params PostImagesParams)
+	// リリースタグのロールバック
+	// (POST /images/rollback)
+	PostImagesRollback(c *gin.Context)
+	// タグの削除
+	// (DELETE /images/tags/{tag})
+	DeleteImagesTag(c *gin.Context, tag ImageTagName, params DeleteImagesTagParams)
+	// メトリクスの取得
+	// (GET /metrics)
+	GetMetrics(c *gin.Context)
+	// リクエストを受け付けられるかの確認
+	// (GET /readyz)
+	GetReadyz(c *gin.Context)
+	// リリース申請一覧の取得
+	// (GET /release-requests)
+	GetReleaseRequests(c *gin.Context, params GetReleaseRequestsParams)
+	// リリース申請
+	// (POST /release-requests)
+	PostReleaseRequests(c *gin.Context)
+	// リリース申請の取得
+	// (GET /release-requests/{id})
+	GetReleaseRequest(c *gin.Context, id ReleaseRequestId)
+	// リリース申請の承認
+	// (POST /release-requests/{id}/approve)
+	PostReleaseRequestApprove(c *gin.Context, id ReleaseRequestId)
+	// リリース申請の却下
+	// (POST /release-requests/{id}/reject)
+	PostReleaseRequestReject(c *gin.Context, id ReleaseRequestId)
+	// リリース履歴の取得
+	// (GET /releases)
+	GetReleases(c *gin.Context, params GetReleasesParams)
+	// リポジトリ情報の取得
+	// (GET /repositories/{name})
+	GetRepository(c *gin.Context, name RepositoryName)
+	// リポジトリ指定でのコンテナイメージ一覧の取得
+	// (GET /repositories/{name}/images)
+	GetRepositoryImages(c *gin.Context, name RepositoryName)
+	// リポジトリ指定でのリリースタグセット
+	// (POST /repositories/{name}/images)
+	PostRepositoryImages(c *gin.Context, name RepositoryName, params PostRepositoryImagesParams)
+	// リポジトリ指定でのリリースタグのロールバック
+	// (POST /repositories/{name}/images/rollback)
+	PostRepositoryImagesRollback(c *gin.Context, name RepositoryName)
+	// リポジトリ指定でのタグの削除
+	// (DELETE /repositories/{name}/images/tags/{tag})
+	DeleteRepositoryImagesTag(c *gin.Context, name RepositoryName, tag ImageTagName, params DeleteRepositoryImagesTagParams)
+	// 予約リリース一覧の取得
+	// (GET /scheduled-releases)
+	GetScheduledReleases(c *gin.Context, params GetScheduledReleasesParams)
+	// 予約リリースの取り消し
+	// (DELETE /scheduled-releases/{id})
+	DeleteScheduledRelease(c *gin.Context, id ScheduledReleaseId)
+	// 予約リリースの取得
+	// (GET /scheduled-releases/{id})
+	GetScheduledRelease(c *gin.Context, id ScheduledReleaseId)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -35,9 +94,23 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(c *gin.Context)
 
+// GetHealthz operation middleware
+func (siw *ServerInterfaceWrapper) GetHealthz(c *gin.Context) {
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetHealthz(c)
+}
+
 // GetImages operation middleware
 func (siw *ServerInterfaceWrapper) GetImages(c *gin.Context) {
 
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
 	for _, middleware := range siw.HandlerMiddlewares {
 		middleware(c)
 	}
@@ -48,11 +121,506 @@ func (siw *ServerInterfaceWrapper) GetImages(c *gin.Context) {
 // PostImages operation middleware
 func (siw *ServerInterfaceWrapper) PostImages(c *gin.Context) {
 
+	var err error
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PostImagesParams
+
+	// ------------- Optional query parameter "dry_run" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "dry_run", c.Request.URL.Query(), &params.DryRun)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter dry_run: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PostImages(c, params)
+}
+
+// PostImagesRollback operation middleware
+func (siw *ServerInterfaceWrapper) PostImagesRollback(c *gin.Context) {
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PostImagesRollback(c)
+}
+
+// DeleteImagesTag operation middleware
+func (siw *ServerInterfaceWrapper) DeleteImagesTag(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "tag" -------------
+	var tag ImageTagName
+
+	err = runtime.BindStyledParameter("simple", false, "tag", c.Param("tag"), &tag)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter tag: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteImagesTagParams
+
+	// ------------- Optional query parameter "force" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "force", c.Request.URL.Query(), &params.Force)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter force: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.DeleteImagesTag(c, tag, params)
+}
+
+// GetMetrics operation middleware
+func (siw *ServerInterfaceWrapper) GetMetrics(c *gin.Context) {
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetMetrics(c)
+}
+
+// GetReadyz operation middleware
+func (siw *ServerInterfaceWrapper) GetReadyz(c *gin.Context) {
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetReadyz(c)
+}
+
+// GetReleaseRequests operation middleware
+func (siw *ServerInterfaceWrapper) GetReleaseRequests(c *gin.Context) {
+
+	var err error
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetReleaseRequestsParams
+
+	// ------------- Optional query parameter "repository" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "repository", c.Request.URL.Query(), &params.Repository)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter repository: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "status" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "status", c.Request.URL.Query(), &params.Status)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter status: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetReleaseRequests(c, params)
+}
+
+// PostReleaseRequests operation middleware
+func (siw *ServerInterfaceWrapper) PostReleaseRequests(c *gin.Context) {
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PostReleaseRequests(c)
+}
+
+// GetReleaseRequest operation middleware
+func (siw *ServerInterfaceWrapper) GetReleaseRequest(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id ReleaseRequestId
+
+	err = runtime.BindStyledParameter("simple", false, "id", c.Param("id"), &id)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetReleaseRequest(c, id)
+}
+
+// PostReleaseRequestApprove operation middleware
+func (siw *ServerInterfaceWrapper) PostReleaseRequestApprove(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id ReleaseRequestId
+
+	err = runtime.BindStyledParameter("simple", false, "id", c.Param("id"), &id)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PostReleaseRequestApprove(c, id)
+}
+
+// PostReleaseRequestReject operation middleware
+func (siw *ServerInterfaceWrapper) PostReleaseRequestReject(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id ReleaseRequestId
+
+	err = runtime.BindStyledParameter("simple", false, "id", c.Param("id"), &id)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PostReleaseRequestReject(c, id)
+}
+
+// GetReleases operation middleware
+func (siw *ServerInterfaceWrapper) GetReleases(c *gin.Context) {
+
+	var err error
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetReleasesParams
+
+	// ------------- Optional query parameter "repository" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "repository", c.Request.URL.Query(), &params.Repository)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter repository: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", c.Request.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter limit: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", c.Request.URL.Query(), &params.Cursor)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter cursor: %s", err), http.StatusBadRequest)
+		return
+	}
+
 	for _, middleware := range siw.HandlerMiddlewares {
 		middleware(c)
 	}
 
-	siw.Handler.PostImages(c)
+	siw.Handler.GetReleases(c, params)
+}
+
+// GetRepository operation middleware
+func (siw *ServerInterfaceWrapper) GetRepository(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name RepositoryName
+
+	err = runtime.BindStyledParameter("simple", false, "name", c.Param("name"), &name)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter name: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetRepository(c, name)
+}
+
+// GetRepositoryImages operation middleware
+func (siw *ServerInterfaceWrapper) GetRepositoryImages(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name RepositoryName
+
+	err = runtime.BindStyledParameter("simple", false, "name", c.Param("name"), &name)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter name: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetRepositoryImages(c, name)
+}
+
+// PostRepositoryImages operation middleware
+func (siw *ServerInterfaceWrapper) PostRepositoryImages(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name RepositoryName
+
+	err = runtime.BindStyledParameter("simple", false, "name", c.Param("name"), &name)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter name: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PostRepositoryImagesParams
+
+	// ------------- Optional query parameter "dry_run" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "dry_run", c.Request.URL.Query(), &params.DryRun)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter dry_run: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PostRepositoryImages(c, name, params)
+}
+
+// PostRepositoryImagesRollback operation middleware
+func (siw *ServerInterfaceWrapper) PostRepositoryImagesRollback(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name RepositoryName
+
+	err = runtime.BindStyledParameter("simple", false, "name", c.Param("name"), &name)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter name: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.PostRepositoryImagesRollback(c, name)
+}
+
+// DeleteRepositoryImagesTag operation middleware
+func (siw *ServerInterfaceWrapper) DeleteRepositoryImagesTag(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "name" -------------
+	var name RepositoryName
+
+	err = runtime.BindStyledParameter("simple", false, "name", c.Param("name"), &name)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter name: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Path parameter "tag" -------------
+	var tag ImageTagName
+
+	err = runtime.BindStyledParameter("simple", false, "tag", c.Param("tag"), &tag)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter tag: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteRepositoryImagesTagParams
+
+	// ------------- Optional query parameter "force" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "force", c.Request.URL.Query(), &params.Force)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter force: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.DeleteRepositoryImagesTag(c, name, tag, params)
+}
+
+// GetScheduledReleases operation middleware
+func (siw *ServerInterfaceWrapper) GetScheduledReleases(c *gin.Context) {
+
+	var err error
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetScheduledReleasesParams
+
+	// ------------- Optional query parameter "repository" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "repository", c.Request.URL.Query(), &params.Repository)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter repository: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "status" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "status", c.Request.URL.Query(), &params.Status)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter status: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetScheduledReleases(c, params)
+}
+
+// DeleteScheduledRelease operation middleware
+func (siw *ServerInterfaceWrapper) DeleteScheduledRelease(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id ScheduledReleaseId
+
+	err = runtime.BindStyledParameter("simple", false, "id", c.Param("id"), &id)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.DeleteScheduledRelease(c, id)
+}
+
+// GetScheduledRelease operation middleware
+func (siw *ServerInterfaceWrapper) GetScheduledRelease(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id ScheduledReleaseId
+
+	err = runtime.BindStyledParameter("simple", false, "id", c.Param("id"), &id)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyScopes, []string{""})
+
+	c.Set(BearerAuthScopes, []string{""})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+	}
+
+	siw.Handler.GetScheduledRelease(c, id)
 }
 
 // GinServerOptions provides options for the Gin server.
@@ -84,31 +652,135 @@ func RegisterHandlersWithOptions(router *gin.Engine, si ServerInterface, options
 		ErrorHandler:       errorHandler,
 	}
 
+	router.GET(options.BaseURL+"/healthz", wrapper.GetHealthz)
+
 	router.GET(options.BaseURL+"/images", wrapper.GetImages)
 
 	router.POST(options.BaseURL+"/images", wrapper.PostImages)
 
+	router.POST(options.BaseURL+"/images/rollback", wrapper.PostImagesRollback)
+
+	router.DELETE(options.BaseURL+"/images/tags/:tag", wrapper.DeleteImagesTag)
+
+	router.GET(options.BaseURL+"/metrics", wrapper.GetMetrics)
+
+	router.GET(options.BaseURL+"/readyz", wrapper.GetReadyz)
+
+	router.GET(options.BaseURL+"/release-requests", wrapper.GetReleaseRequests)
+
+	router.POST(options.BaseURL+"/release-requests", wrapper.PostReleaseRequests)
+
+	router.GET(options.BaseURL+"/release-requests/:id", wrapper.GetReleaseRequest)
+
+	router.POST(options.BaseURL+"/release-requests/:id/approve", wrapper.PostReleaseRequestApprove)
+
+	router.POST(options.BaseURL+"/release-requests/:id/reject", wrapper.PostReleaseRequestReject)
+
+	router.GET(options.BaseURL+"/releases", wrapper.GetReleases)
+
+	router.GET(options.BaseURL+"/repositories/:name", wrapper.GetRepository)
+
+	router.GET(options.BaseURL+"/repositories/:name/images", wrapper.GetRepositoryImages)
+
+	router.POST(options.BaseURL+"/repositories/:name/images", wrapper.PostRepositoryImages)
+
+	router.POST(options.BaseURL+"/repositories/:name/images/rollback", wrapper.PostRepositoryImagesRollback)
+
+	router.DELETE(options.BaseURL+"/repositories/:name/images/tags/:tag", wrapper.DeleteRepositoryImagesTag)
+
+	router.GET(options.BaseURL+"/scheduled-releases", wrapper.GetScheduledReleases)
+
+	router.DELETE(options.BaseURL+"/scheduled-releases/:id", wrapper.DeleteScheduledRelease)
+
+	router.GET(options.BaseURL+"/scheduled-releases/:id", wrapper.GetScheduledRelease)
+
 	return router
 }
 
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/8xWS4/cRBD+K6jg6Fk7uywPn3hoBSMRFIXcVquox1O2O3G7e7vbsLOjkcCWAHHhguAC",
-	"Bx6C5SFAyiWICH5ME8HPQNX2vD0wQRwizaGn3FX1fV897CkkUihZYmkNxFPQeF6hsS/JMUdv4IJlaG62",
-	"ZjIksrRY+iNTquAJs1yW4R0jS7KZJEfB6PSUxhRieDJcZgjbpyYcUtRbLIPZbBbAGE2iuaI4EINrvvO/",
-	"B67+xdW/u/pnV//qmsY175O9/snVV/SI/n7qmvdc/SVQFI1GydK0sFFrqW92lv8N9glF7cVcX7nmW8Lc",
-	"fE5QCfADV993zQ8e6meuuecPC8DBQtn/gJFbFGYvjSmRnSiEGJjWbNIP/h7Ba951zQeu/spTIPB/3H/7",
-	"r6+/2U1hFnSQPJRWm3i6tzJfUJzmewhAaalQ267jBBpDyOPpHLmxmpdZV+TzimscQ3y6uHgWgOW2oJst",
-	"iAVlObqDiYUALgbGSlXwLPfq8jHEUDx3KQwf5YejMU988FaxHgr9+uymMOZZNy0bDAJQlclxfJv5p6nU",
-	"gk4wZhYHlgtcYl+6aFTScCv15HbJBPaGNfxy9UFZiRFqX3yWmbWW2XLdaI91jb17F34bSTBnusprpRyt",
-	"oH3l4KVFXbIC4pQVBvsrlOkqw0iXF2mZqmWFaG/E0w3JbWtcZ9cf9u7h8Vt5epkeX5scddO8QXmLA6Xc",
-	"r6suxPGxuDyX51qbIwpOk16mcj7fLPF3UTBeQAy5YNZUTz/7QkaGg0QKCKCtMrzKtZwwUz1xne7k3DAI",
-	"oNLezVpl4jDMuM2rEbmF80jwKAv1z4+uXrwxhAAKnmC3hbrs14e39kkXGiwwsYNlawyYUuGokKNQMGNR",
-	"h68NXz55/Y0T32udqAbJo0BmcGC9tm+iNi3cawcRXZUKS6Y4xHB0EB1E1GLM5r7WYbs66Zih3X9gu4X2",
-	"zo8PP/z44W+fgE+i/ZIdUu1eQTtsI2+8TQ6jaNe2XdwLN/a5L0PKqsL+u+v628ov1koIpiePRKYd9dP2",
-	"xQJntGyk6ZNndz9sSXJDmlVN5h8Hk92cVr4fwvWPh9njpOo/arCppPdFTT0K8el0ZSriMCxkwopcGhsf",
-	"RVEEs7OF/759uRz5NiGF6N0tF5U6fCa6+zzP0hxms78DAAD//4XE0RG9CQAA",
+	"H4sIAAAAAAAC/+xc+3MTR57/V1Rz+6OMhAm5i6qu6ryJb/Fu2KOMr7JXlE81ltrSBEkjZkYJxqUqz4iH",
+	"AHttHAzhsUt42uAgm4XkDBbwx7RHsn/yv3DV3fPomekezdgyG1KpSgXZVk9/+/v8fB8900JOLlflCqho",
+	"qpCZFqqiIpaBBhT8U16ZGq1V8Ceg5hSpqklyRcgImlIDCai3oL4C9Tmor0HjPTTWobG4tfk91K917ryH",
+	"ehPqt6G+arbubd+f3V5pdq9vQmNx+/11qN8SkoKEHnSmBpQpISlUxDIQMmi/rFKrCElBzRVBWSQ7T4q1",
+	"kiZkJsWSCpKCNlVFX52Q5RIQK0K9nhQmZSUHglRC4xFs3IeNNjQ2oN7q3J0x380iqgmx+jI0DPPRDajf",
+	"2m037V+2oP4M6vP4/w+hfs/7kLXE8OejCahfhcZl8/KVnVuPoL4EjVloXN1tX+acipAX80xSWSyAMbHw",
+	"Z/wM/9EI2YRme9eqqBXdTTWxICQFBZypSQrICxkkMpoEa0tVU6RKAe+ogBIQVTAKztSAqo3kGQxtPMP/",
+	"taHxunv95fbqVai3EiNfsCmQ8qEETMpKWdTQ9yrap58IDhOkigYKQLFIqsqqpMnKFJsN2yvPzdZt2FiC",
+	"xn0sqFWoL5ut2913T6F+E0kPkfs3JLpGEzaemQtzbFrxP/HYhf6Wr5VAfpTwjcWwrTfN7qvzNNsOlGF1",
+	"shyo2u/lvASwCWM9Ui2hol/k5IoGKvijWK2WpJyIiE19rcrY0N39fqeASSEj/EvK9RAp8lc1NWJpJ9mU",
+	"ryW2X9iEjQYRATTWoLGC/oR+vAsbl6DxUAioX78JHvUqd6Va03rRbmk4l2RMs1qVKyrhNFAUWRm1ftM3",
+	"wofRU5mkGiuw8RSRitxTA/MYO6nGj5jUv8HGS/yB4rGtDHugUdJAWY2kFkLdUU1RUcQpNvEvEXmNi7Bx",
+	"hfawWxsz20+WuUdICkUg5q3gNFRg+vzQBxuL0HgOGw8xw/4PNh5jN/GEOHSot7o/ze7of+0uL3aW1nfb",
+	"zeMjJ0+iOGf+8MpcaCL3nyZuPuAYaK/1l4HPxVyRQdyxkTEcaYIU3Aune7d9Gerv0Nf0tQQiarfdpALR",
+	"/A3z3U0/YaBSKwuZU2hTISmgRcJ4MuDHiBaLeakCVLXvyjtqP5lna7RlGYvm/E2oXyMQAskDx1V0Rr3V",
+	"ffBm+9lc96eFzt/v7rabjG+igH3eFdTR9BHEEYZj6fsZ6cdHdSp8G/WSqx4wvV9KUWnuYZo05XugWK6A",
+	"/5oUMqci+BdVqCcjHfFECWGp8bghin3A3XZzZ+a2ubGB0W6ok5nRLRyb6Nwy0Nep3Wg0TCz2N48W4tHg",
+	"jOHlJTb5q8TY9+Du0FEs7Rg4qYlaTQ2eqVbNixrIuzmBP6u55yG+VskVxUoBL+jcfIAynrV32y/uexOH",
+	"VTu/mMXPOo9FdB4/4h40dPPR5c6dV/hYezu2RbSQFBx6+O4en//APEsUl2K+eNx5/iqKS7ETgAOg1n70",
+	"SGVS5hLsJg+dxgXzhxdh1PpTgr7TfNK3AYtqRs4Rg2T1wGnmqUeQ7l7qUbcNARsxAcqZ6cgw+QF6TmNV",
+	"SApVRa4CRbMyJnxUkM9qYoHhHbY2v9/a+CsqHqDCxz1i1rvt5vajS52ldaqKcBHq97c2ZnYaK1Bv2atW",
+	"zUcvOks3iYe0fXAL6u+t6oENsn126wfUSWFSlEoRaXyGPcfDfyKxZaCqIoltge9aCWtW4tQbKISYGPkC",
+	"OWW/PnyPA/cMbLQTyLnjxw2M5BNQXzEXZqH+PSGXVfCwU+1TDonIZ0paCX2TKJSzUJ74GuQ0ISmcHVA1",
+	"uVqSCkVsGYhwofRv58qqNFEcnMhLOfzwY0AsacXgmTrPNzuvNrt3Hm4/ucHXQdWJTbaDl08L470OYa2i",
+	"zmBR4T9EPSmQVC0y2uCTmpcKVqIekG21phZBPitqnvIFClQDmoQLLgx1sL1ytmKVfALfUaVz9B8qtfIE",
+	"wilJwbaGqJrpYx9ebj0+SEnSPil9LorVhKEsdUFISqmIJafcx9KgglIrgLRSOTtZmaxi0pwaS2gdjgU2",
+	"Wp3ZS2br9m67qYkFZAcJQjop2T6F+n2MDRGk79x4TWzaXoJMpSJjuny8cTkwXucrQVXU0GmFjPC/alEc",
+	"PPpp5pQ4MJke+Gx8+tNP6r9jydyu+va/zIwxHHYgiF3XcCAgXHqP3IWNsdiewqnGUlHS0mWfSWPWETfZ",
+	"ufkYI7ZVb9HvFi4QNxmx2Vjcenu301wgsD0xmB5MOORTMBCzYM1cmCVPt7d0PPx5Qno0I9OIUnl/z1bL",
+	"04NHvy1Onps8enjqiFWG8ij8GNaLKC7ybPno0fK5M/IZRVGPuAquxs54KE8UsyhFE64KwTjllisy0/0q",
+	"VvAdZ64Icqc5KkWeQKsUL82ydqEyOvPynHnn7w4Z9vLo6uF4PovQSFwepZC6U/QJsjgY2FD1aYo4nSz5",
+	"HDXOJWkW+simHLNLDyMM2kA6M90zWwmRpFgqAYUZcEKiI0E8Pav64fAJkSESkmmWkkMlBUUulSbE3Gmc",
+	"FyKzH2fIu6qAbyS5pmZDiLUemQ2Da53L7x2l5bWKdttN+8dVaDSRyXi7Iw68jMAYi6i9IgzOQVXcmXO5",
+	"qdZyOaI9bmpNAHhNAUyGuuHCZpsUoznEjhPxOKPKNSUHskxHzwsAFIBipxNYrvotqL/G1YuWk05gV/0S",
+	"Nm4ibI4yrSYp43Sv37Mj2z0ndJsLq9CYiZdC+OzfapS5wqftwCPhpAVcKH7QOM6n+Enbkh018LgR26h4",
+	"TgRnthEcSTCOed1JBZzVsrmaorLy2c6P9xHrG7ctsGcskmIZwRg2UmolyHpUk3La3s4aBBvWunf17tLj",
+	"uLYWJyBYJYoewnQeHOQ1Ziif37i+G85vGhTa/N5tNz3FtZYvoyQs8cdqZPfRDz8mFj7HS1gxkMK7QahJ",
+	"WnTZkqxKlYJtwH58ZJUS0RQAgR1e+I+yYeMR+mwsE7wSK13v4SGJLfWGSPoyDYxtoq2yKZPE8KjvDod4",
+	"DNwiJ+nIiMXDoG5h5eHrFtV/7t3M4abGICflY4Yne83EVA884QOMmBSSHLBSwtnOrI6LQHbZOQL/kwI4",
+	"W5UUoMY6QF9gTc8gjaUTO/LbqzjM7ZHmYXiDfSkjuyMwOzLGjgYRyJZEqlSaeC9kriQyOODp0P7tloXy",
+	"q6CSJ1mmWK0q8jeAhHBkdfgj0TQbWDGbFzx44mESDVWc6ah9oQyWq3HwgpOJeHTLp6AeOwp6ItvV9HRG",
+	"ZGol2lTWx1UB8pr7vme8UJKB0Y3df72Elz9Faxu4hu1dwiwQR3cHM3r0CpBHWfnVoH1Xc+hCDUuLeipb",
+	"bzDrHQvgxUBf4hgbPzpzFdFgpLsP19D4qNLTEGSfPdgO5B0cgxAEPrLlmiZOSCVJY2g37nl7H0xNqLZI",
+	"X9icXzMXnghJx5se/++xod9/OSwkhZHj9meWw5TKeO8SyKqaImqgMBWKJtFeWxtz5vxa0EZw0tCi5d+5",
+	"8brzcmm33UQeO4Fa4Z+kP6OLl3qtIp2pYR6oCWyHdoKIAscdbI0z+EcrKaQaUu+JJyd2YB8bbYTTcOex",
+	"zFNzGwfs+OE5kyeK4IzKoSmQ5FJTwz7mLXtt363SOkW67tM3cbtoNUXiDe66ZmC1KtB3k2wVZGpFUnC4",
+	"6diMxxpC7aVH3dSjRddxihqxREpBRHZ91NOi7C5c7F5/IcTRCFJwZCRibLa69ckAl0KrjIG+fZRiUEi5",
+	"sZIDpRIfx+YUIMYFx/aamHkHoXs/eQeyIv07ZtaKfkk/zVhkxdTL7MwF5GpxedCn1KXvlVJUS2PA/7jF",
+	"0vCs3gd2IqYxnFTiQDMIpVapkE+4NAvydN6QdM0jRg7xwfMGmttUGkFZoceMKZcT8CUR/A0bzPEnbrhj",
+	"Cf6sNTqcC5Ddi42MvUK4wAN1biGOA3zoTrJ58YLZCnG8k4pcznIrMPPvzLsr7DG/CBU6GkzYa32Xj8iM",
+	"8zU87LzuAIjoxX2ZS7uvrx6xohiOREi+SLOMJoESpSuigPyQIwC5miJpU0jgZXtAS/oTYCDZoRMjCdwj",
+	"baMRJ0ba2EqINa14SKxK2dNgSs1OSiWQcBxTd3HdfNBAQG5pPXFyeCw7Ovzl8NDJ4ezY0B+yQydGsn8a",
+	"/h80O7sMG/NuE9ZY7N7a3Jn9B3X9i0z0uvd6/jIwdGJkAJHsqjw5Qj0pTABRAcpQjcwKkZ/+0/a9f/wK",
+	"DbN6T/nHr8bCj/f1t9qhr7897RyvZV74cefG1Z25n6C+3H37D3NhDhqLnUd3t1fa1Owvhj94e5fMoqZV",
+	"SdNfslIiNBIo5kh/rIzgeEYolkVNrX3yr/9RQL84lJPL7tGPSYo8Jaq1xHH0naKkihiclqxnq5lUqiBp",
+	"xdoEWpaynyTEGdjuXl8ZOjEiJIWSlAPW4KK1+/GRsSjbpVRQAjltwPXZA2K1mpooyROpsqhqQEl9OfL5",
+	"8J9PDtNTBCpAK8goMdH1b4CiEnIPH0pbzdmKWJWEjHDkUPpQGvkTUStiHU4V8WTWOfS5AJiZ9k3YeI5O",
+	"iZDOrHl1aevtXXu2+7w1akDNuWw/m9teaW9tzG0/0eGMQWapsekaD3BZnjxojZoscMdGnO4ZusQm/AFo",
+	"xyzqfFecBtPpvs2Fki3Yc7c9ju5xDELm1DhCAuWyiKCNZ8DOzmvQgJ9cQaJFnmocLU9JzvQJm//hQyh6",
+	"i7TdWMxz5ktYvGOxxPleyndBq550r4r2Wuq9flav0zyJfhibXZgQUuaTexWCfAZJ99cwDLSqcLFnt4Ka",
+	"eUJWXe7SV5U590bcr6Ssq8z1cfqO5BSfrdQ1ypT3DmV9L4L1342pJ4XB9GDvddyR8r6pBl+QDHVwDSfl",
+	"jJZkpmPoyGL3zivz8hwpsnjuQXivS3Sam8SvdV+vQ+MKVombRE+cTje+MbGKn9biLA9ToVF3NuaXY6hB",
+	"liHwhTANLpctYOCxFi4Z9KfUtCYW6kQmJaD1vB2Pbws5IxrupXj3O9uXnm29/Q7Pl5F771YEgTNGr4v2",
+	"awl8Bf7f/fOcKH+19wpK6gtMOJEVGTOMZ/F4T8vgfzF+2K7rYh6y/W2sQ3peE1BH61NloClSjh/Zjo2N",
+	"nUgErjVvIsSAELR5rQ31l+alN0jCjU3/EFLjvlXJM9ZIwyJxQpHLQCuCmpqwyqTGc/JU8+0Dsz2P2jXW",
+	"fS4PSuFAj+MW+T2hhwbOaqlqSZR8oCNw58mPL3oT7D/mfhWAA1X8zGTE4QBswSVIPmwc+gpf7yN8tloU",
+	"7ogQLkTP6J4hrkDzgb2qF+C051DXtjZmSMzfufFdYGLVrdgF5D5KDra34Oq/wFxPCkfTR/a0MlResYeA",
+	"w0VJsge6NcbJBQIlQmOxc2Md++DzOz9cRGGQC0e93S8Gcup3v9W8sMJssLLejeKtk/Fft8GbVWh1r/zc",
+	"uXDVR4Gj4PyNnZJb8C5lH2YV9hZ1eFe/DwJdeLq3DMdDji6WYuYAi97KrweUeYC/cdXRZLvlbdW7t2cu",
+	"bG0+xrhg1i6U27ONnPJ/EOIFlT426me/kCSI/g/HFewHkCtbkCynk5qW8vV4nieqqxH2bwMfgFW9lT8W",
+	"IAu8RYmAMibbU2Qf4H/x1p524Vlp0KjY4cRtST1hGrbPeh1zxR53hXkNjdXRQ51yaMy7PUFczUajA9FM",
+	"echi2ceiW4SvMe0xRaLNgeoF27TnXm1tXI0ghlFgXfX6SCzcPle4FKJBMOs6UHwI9ivBXocT1FUCA8f5",
+	"K44X3dr8ubO0ztmxJJUljf0mvMF0UiiLZ6UywmCH0+gnqWL9xHo3nJ8oqwzkEtZKUPconCGDxMgXCXzv",
+	"6Io5/wgN5NiypF+ixCKdPEcIfSFbKMX7QYQHCwUtHjDioLW9YyTuBbvUNOILHzjE1WTe0BsCfTN6pBGt",
+	"cGBCqfpexBB4DUlfBRGYKmTKwjnCXlCJ50WKNiYJyLNXV2QPDsqbEzzp+TqjKEL8BXZYfGJ072e39tV8",
+	"2Z+UebG/D2JkQcQP1QJiKMJvzaCD1uLYfSK+e/H2jj4qFY/VvoqkvL/YNlRkPYjRoQrRiWhdq34EoB59",
+	"L04jyi+3X0tLKixw9btb5bfe5F76W44XHOiZtTFv2JBQs/WmieKSdVmn1SuD888b/kpSOWeS+EDL6PsY",
+	"2N2brfDfatcvs+HOzjLgnE2N5QOD+uvUYHlej6gsSXHZOj1/AxpXOj83SW+OfL+z0YT6e9jYtDR+47nn",
+	"xZ+ckhvxdowp4/2L4QNIgfDf5gVHCsk43iKyS/i4WMRV0bg+nfEe/Pq4r4vqzuyeGkdOiB56PTWObFwF",
+	"yjf2hu7AZiaVKsk5sVSUVS1zJJ1O49hpER11Ys91VpL1JqjexT3as9rj6j2uNjGdcfheTq/GWugUJbmO",
+	"mlrsLnOEF1zmjEFaX6V60PVxznu6ztaqg5+mT38mFSbReOb/DwBhaaq6ImQAAA==",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
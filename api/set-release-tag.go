@@ -2,78 +2,840 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 type SetReleaseTag struct {
-	RepositoryUri string
-	TagName       string
+	Repositories *RepositoryRegistry
+	// 付与するタグのテンプレート（プレースホルダーを含まないタグはリリースごとに付け替える）
+	TagNames []string
+	// タグが変更不可（IMMUTABLE）のリポジトリでのリリース方法
+	ImmutableStrategy RepositoryInfoImmutableStrategy
+	// 権限定義（nil の場合はすべての操作を許可）
+	Policy *Policy
+	// リリース申請の有効期限
+	ApprovalTtl time.Duration
+	PageSize    int32
+	MaxImages   int
+	// ロールバックで戻せるリリース数（リポジトリごと）
+	RollbackDepth int
+	// リリース履歴（nil の場合は記録せず、ロールバック・リリース申請・予約リリースは使えない）
+	Releases *ReleaseStore
+	// Webhook の通知（nil の場合は通知しない）
+	Notifier *Notifier
+	// Prometheus のメトリクス（nil の場合は記録しない）
+	Metrics *Metrics
+	// リクエストを受け付けられるかの確認（/readyz）
+	Readiness *ReadinessChecker
+	// コンテナイメージ一覧のキャッシュ（nil の場合はキャッシュしない）
+	Images *ImageCache
+	// リージョンごとの ECR クライアントの提供元
+	EcrClients EcrClientProvider
+	// ECR API 1 回あたりのタイムアウト（0 の場合は設定しない）
+	EcrCallTimeout time.Duration
+	// 予約リリース 1 件あたりのタイムアウト（リクエストは OperationTimeout Middleware で設定。0 の場合は設定しない）
+	OperationTimeout time.Duration
+	// 予約リリースを実行するか（false の場合は予約を受け付けない。取得・取り消しは可）
+	SchedulerEnabled bool
+	// タグ名の生成・リリースタグの付け替え・削除とリリース履歴の記録を直列化（{seq} の採番とロールバックで戻す先をリリース履歴から決めるため）
+	releaseLock sync.Mutex
+	// WaitForReleases の呼び出し後（releaseLock で保護）
+	releasesClosed bool
+	// リリース申請の承認・却下を直列化
+	approvalLock sync.Mutex
 }
 
-func NewSetReleaseTag(repositoryUri string, tagName string) *SetReleaseTag {
-	return &SetReleaseTag{
-		RepositoryUri: repositoryUri,
-		TagName:       tagName,
+// SetReleaseTag の生成パラメーター（Repositories・TagNames 以外は省略可。省略した機能は無効）
+type SetReleaseTagOptions struct {
+	Repositories *RepositoryRegistry
+	TagNames     []string
+	// 省略時は fail
+	ImmutableStrategy RepositoryInfoImmutableStrategy
+	Policy            *Policy
+	// 省略時は DefaultApprovalTtl
+	ApprovalTtl time.Duration
+	// 省略時は EcrDescribeImagesMaxPageSize
+	PageSize  int32
+	MaxImages int
+	// 省略時は DefaultRollbackDepth
+	RollbackDepth int
+	Releases      *ReleaseStore
+	// 予約リリースを実行する場合は true（StartScheduler を呼び出すこと）
+	SchedulerEnabled bool
+	Notifier         *Notifier
+	Metrics          *Metrics
+	EcrClients       EcrClientProvider
+	// ECR API 1 回あたり・予約リリース 1 件あたりのタイムアウト（省略時は設定しない）
+	EcrCallTimeout   time.Duration
+	OperationTimeout time.Duration
+	// コンテナイメージ一覧をキャッシュする時間（省略時はキャッシュしない）
+	ImageCacheTtl time.Duration
+	// /readyz の確認結果をキャッシュする時間（省略時は毎回確認する）
+	ReadinessTtl time.Duration
+}
+
+func NewSetReleaseTag(opts SetReleaseTagOptions) *SetReleaseTag {
+	if opts.ImmutableStrategy == "" {
+		opts.ImmutableStrategy = Fail
+	}
+	if opts.ApprovalTtl == 0 {
+		opts.ApprovalTtl = DefaultApprovalTtl
+	}
+	if opts.PageSize == 0 {
+		opts.PageSize = EcrDescribeImagesMaxPageSize
+	}
+	if opts.RollbackDepth == 0 {
+		opts.RollbackDepth = DefaultRollbackDepth
+	}
+	s := &SetReleaseTag{
+		Repositories:      opts.Repositories,
+		TagNames:          opts.TagNames,
+		ImmutableStrategy: opts.ImmutableStrategy,
+		Policy:            opts.Policy,
+		ApprovalTtl:       opts.ApprovalTtl,
+		PageSize:          opts.PageSize,
+		MaxImages:         opts.MaxImages,
+		RollbackDepth:     opts.RollbackDepth,
+		Releases:          opts.Releases,
+		Notifier:          opts.Notifier,
+		Metrics:           opts.Metrics,
+		Images:            NewImageCache(opts.ImageCacheTtl),
+		EcrClients:        opts.EcrClients,
+		EcrCallTimeout:    opts.EcrCallTimeout,
+		OperationTimeout:  opts.OperationTimeout,
+		SchedulerEnabled:  opts.SchedulerEnabled,
+	}
+	s.Readiness = NewReadinessChecker(opts.Repositories, s.CheckRepositoryAccess, opts.ReadinessTtl)
+	return s
+}
+
+// リリース・ロールバック・タグ削除の結果をログに出力（失敗は error）
+func logRelease(ctx context.Context, record Release) {
+	fields := []zap.Field{
+		zap.String("operation", string(record.Operation)),
+		zap.String("result", string(record.Result)),
+		zap.String("repository", record.Repository),
+		zap.String("tag", record.Tag),
+		zap.String("source", record.SourceTag),
+		zap.String("digest", record.Digest),
+		zap.String("previous_digest", record.PreviousDigest),
+		zap.String("actor", record.Caller),
+	}
+	if record.Tags != nil {
+		fields = append(fields, zap.Strings("tags", *record.Tags))
+	}
+	if record.Id != 0 {
+		fields = append(fields, zap.Int64("release_id", record.Id))
+	}
+	if record.ReleaseRequestId != nil {
+		fields = append(fields, zap.Int64("release_request_id", *record.ReleaseRequestId))
+	}
+	if record.ScheduledReleaseId != nil {
+		fields = append(fields, zap.Int64("scheduled_release_id", *record.ScheduledReleaseId))
+	}
+	if record.Message != nil {
+		fields = append(fields, zap.String("error", *record.Message))
+		LoggerFromContext(ctx).Error("release", fields...)
+		return
+	}
+	LoggerFromContext(ctx).Info("release", fields...)
+}
+
+// ECR クライアントの提供元が設定されていない場合のエラー
+var ErrNoEcrClient = errors.New("ECR クライアントが設定されていません")
+
+// リポジトリのリージョンの ECR クライアント（呼び出しをログ・メトリクスに記録する）
+func (s *SetReleaseTag) ecrClient(repository Repository) (ECRAPI, error) {
+	if s.EcrClients == nil {
+		return nil, ErrNoEcrClient
+	}
+	uri, err := ParseRepositoryUri(repository.Uri)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.EcrClients.Client(uri.Region)
+	if err != nil {
+		return nil, err
+	}
+	return InstrumentECR(WithEcrCallTimeout(client, s.EcrCallTimeout), s.Metrics), nil
+}
+
+// 停止処理（WaitForReleases）の開始後にリリースタグを付け替え・削除しようとした場合のエラー
+var ErrReleasesClosed = errors.New("停止中のため、リリースタグの付け替え・削除は開始できません")
+
+// タグが変更不可（IMMUTABLE）のリポジトリでリリースタグを付け替えようとした場合のエラー
+var ErrImmutableTag = errors.New("リポジトリのタグが変更不可（IMMUTABLE）のため、リリースタグを付け替えられません")
+
+// エラーレスポンスに含めるリクエスト ID（RequestLogger を通っていない場合は省略）
+func requestIdOf(c *gin.Context) *string {
+	requestId := RequestId(c)
+	if requestId == "" {
+		return nil
 	}
+	return &requestId
 }
 
 // エラーメッセージ返却用
 func sendError(c *gin.Context, code int, message string) {
 	selectErr := Error{
-		Message: message,
+		Message:   message,
+		RequestId: requestIdOf(c),
 	}
 	c.JSON(code, selectErr)
 }
 
+// 処理が失敗した場合のエラー返却用（タイムアウトは 504、呼び出し元が取り消した場合は 499、それ以外は 500）
+func sendServerError(c *gin.Context, message string, err error) {
+	ctxErr := c.Request.Context().Err()
+	switch {
+	case errors.Is(ctxErr, context.Canceled) || errors.Is(err, context.Canceled):
+		sendError(c, StatusClientClosedRequest, fmt.Sprintf("呼び出し元がリクエストを取り消しました : %s", message))
+	case errors.Is(ctxErr, context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded):
+		sendError(c, http.StatusGatewayTimeout, fmt.Sprintf("処理が時間内に完了しませんでした : %s", message))
+	default:
+		sendError(c, http.StatusInternalServerError, message)
+	}
+}
+
+// タグの付与に一部失敗した場合のエラー返却用
+func sendTagApplyError(c *gin.Context, message string, tagApplyErr *TagApplyError) {
+	appliedTags := tagApplyErr.Result.AppliedTags()
+	failedTags := tagApplyErr.Result.FailedTags()
+	selectErr := Error{
+		Message:     message,
+		AppliedTags: &appliedTags,
+		FailedTags:  &failedTags,
+		RequestId:   requestIdOf(c),
+	}
+	c.JSON(http.StatusInternalServerError, selectErr)
+}
+
+// 付与する順のタグテンプレート（リリースタグが既に対象イメージに付いていれば何もしないよう、プレースホルダーを含まないタグを先にする）
+func (s *SetReleaseTag) tagTemplates() []string {
+	templates := FixedTagNames(s.TagNames)
+	for _, v := range s.TagNames {
+		if IsTagTemplate(v) {
+			templates = append(templates, v)
+		}
+	}
+	return templates
+}
+
+// リリース時に付与する順のタグテンプレート（タグが変更不可のリポジトリでは ImmutableStrategy に従う）
+func (s *SetReleaseTag) releaseTagTemplates(mutability types.ImageTagMutability) ([]string, error) {
+	if mutability != types.ImageTagMutabilityImmutable {
+		return s.tagTemplates(), nil
+	}
+	if s.ImmutableStrategy != UniqueTags {
+		return nil, ErrImmutableTag
+	}
+	// 毎回新しいタグになるプレースホルダーを含むタグのみ付与
+	var templates []string
+	for _, v := range s.TagNames {
+		if IsTagTemplate(v) {
+			templates = append(templates, v)
+		}
+	}
+	if len(templates) == 0 {
+		return nil, ErrImmutableTag
+	}
+	return templates, nil
+}
+
+// 起動時にリポジトリのタグの変更可否を確認（確認できなかったリポジトリはリクエスト時に改めて確認）
+func (s *SetReleaseTag) CheckRepositories(ctx context.Context) {
+	logger := LoggerFromContext(ctx)
+	for _, v := range s.Repositories.List() {
+		ecrClient, err := s.ecrClient(v)
+		if err != nil {
+			logger.Warn("failed to create ECR client", zap.String("repository", v.Name), zap.Error(err))
+			continue
+		}
+		mutability, err := TagMutability(ctx, ecrClient, v.Uri)
+		if err != nil {
+			logger.Warn("failed to check tag mutability", zap.String("repository", v.Name), zap.Error(err))
+			continue
+		}
+		if mutability != types.ImageTagMutabilityImmutable {
+			continue
+		}
+		// リリースできない場合はリリースとロールバックが 409、できる場合はプレースホルダーを含むタグのみ付与
+		_, err = s.releaseTagTemplates(mutability)
+		logger.Info("repository has immutable tags", zap.String("repository", v.Name), zap.Bool("releasable", err == nil))
+	}
+}
+
+// 呼び出し元（リリース履歴の記録用。認証していない場合はクライアントの IP アドレス）
+func caller(c *gin.Context) string {
+	if principal, ok := PrincipalFromContext(c); ok {
+		return principal.Subject
+	}
+	return c.ClientIP()
+}
+
+// リリース履歴・メトリクスの記録と Webhook の通知（記録・通知に失敗してもリリース自体の結果は変えない）
+func (s *SetReleaseTag) recordRelease(ctx context.Context, record Release, tagResult *TagResult, err error) {
+	record.ReleasedAt = time.Now()
+	record.Result = Success
+	if tagResult != nil {
+		record.Digest = tagResult.Digest
+		record.PreviousDigest = tagResult.PreviousDigest
+		if len(tagResult.Tags) > 1 {
+			tags := tagResult.AppliedTags()
+			record.Tags = &tags
+		}
+		if tagResult.Unchanged {
+			record.Result = Unchanged
+		}
+	}
+	if err != nil {
+		message := err.Error()
+		record.Result = Failure
+		record.Message = &message
+	}
+	if s.Releases != nil {
+		addErr := s.Releases.Add(&record)
+		if addErr != nil {
+			LoggerFromContext(ctx).Error("failed to save release history", zap.Error(addErr))
+		}
+	}
+	logRelease(ctx, record)
+	s.Metrics.observeRelease(record)
+	s.Notifier.Notify(NewWebhookEvent(record))
+}
+
+// リリース時に付与するタグ名を生成（source は {source} に入れるタグまたはダイジェスト。タグが変更不可でリリースできない場合は ErrImmutableTag）
+func (s *SetReleaseTag) releaseTagNames(ctx context.Context, ecrClient ECRAPI, repository Repository, source string) ([]string, error) {
+	mutability, err := TagMutability(ctx, ecrClient, repository.Uri)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := s.releaseTagTemplates(mutability)
+	if err != nil {
+		return nil, fmt.Errorf("リポジトリ（%s）のタグの設定ができません : %w", repository.Name, err)
+	}
+	tagNames, err := ResolveTagNames(ctx, ecrClient, repository.Uri, templates, source, time.Now(), s.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("付与するタグの生成が失敗しました : %w", err)
+	}
+	return tagNames, nil
+}
+
+// releaseLock を取得（WaitForReleases の呼び出し後は取得せずに ErrReleasesClosed を返す）
+func (s *SetReleaseTag) lockReleases() error {
+	s.releaseLock.Lock()
+	if s.releasesClosed {
+		s.releaseLock.Unlock()
+		return ErrReleasesClosed
+	}
+	return nil
+}
+
+// 停止時に付け替え中のリリースタグの完了を待つ（以降の付け替え・ロールバック・タグ削除は ErrReleasesClosed で失敗する。元には戻せない）
+func (s *SetReleaseTag) WaitForReleases() {
+	s.releaseLock.Lock()
+	defer s.releaseLock.Unlock()
+	s.releasesClosed = true
+}
+
+// 付与するタグ名を生成してリリースタグを付け替え、リリース履歴に記録
+// （record には Tag・結果以外の項目を指定する。タグ名の生成に失敗した場合も失敗として記録する）
+func (s *SetReleaseTag) applyReleaseTags(ctx context.Context, ecrClient ECRAPI, repository Repository, selected types.ImageIdentifier, record Release) (*TagResult, error) {
+	// 呼び出し元が既に接続を切っていればタグを付け替えない（付け替え始めたら途中で止めない）
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// {seq} を同時に採番しないよう、タグ名の生成から記録までを直列化
+	err := s.lockReleases()
+	if err != nil {
+		return nil, err
+	}
+	defer s.releaseLock.Unlock()
+	// タグ名を生成できない場合は先頭のテンプレートを記録
+	record.Tag = s.TagNames[0]
+	tagNames, err := s.releaseTagNames(ctx, ecrClient, repository, record.SourceTag)
+	if err != nil {
+		s.recordRelease(ctx, record, nil, err)
+		return nil, err
+	}
+	record.Tag = tagNames[0]
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	mutationCtx, cancel := mutationContext(ctx)
+	defer cancel()
+	// テンプレートから生成したタグはリリースの記録として残すため、別のイメージから付け替えない
+	var markerTagNames []string
+	fixedTagNames := FixedTagNames(s.TagNames)
+	for _, v := range tagNames {
+		if !contains(fixedTagNames, v) {
+			markerTagNames = append(markerTagNames, v)
+		}
+	}
+	tagResult, err := SetTagExclusive(mutationCtx, ecrClient, repository.Uri, tagNames, markerTagNames, selected)
+	s.invalidateImages(repository, tagResult)
+	s.recordRelease(ctx, record, tagResult, err)
+	return tagResult, err
+}
+
+// タグの付け替え・削除後にコンテナイメージ一覧のキャッシュを無効化（一部のタグの付与に失敗した場合も含む）
+func (s *SetReleaseTag) invalidateImages(repository Repository, tagResult *TagResult) {
+	if tagResult != nil {
+		s.Images.Invalidate(repository.Name)
+	}
+}
+
+// リリースタグ付け替え失敗時のエラー返却
+func sendReleaseError(c *gin.Context, message string, err error) {
+	var tagApplyErr *TagApplyError
+	switch {
+	case errors.As(err, &tagApplyErr):
+		sendTagApplyError(c, fmt.Sprintf("%sが一部失敗しました : %s", message, err), tagApplyErr)
+	case errors.Is(err, ErrImmutableTag), errors.Is(err, ErrTagInUse):
+		sendError(c, http.StatusConflict, fmt.Sprintf("%sが失敗しました : %s", message, err))
+	case errors.Is(err, ErrReleasesClosed):
+		sendError(c, http.StatusServiceUnavailable, fmt.Sprintf("%sが失敗しました : %s", message, err))
+	default:
+		sendServerError(c, fmt.Sprintf("%sが失敗しました : %s", message, err), err)
+	}
+}
+
+// リリース実行計画（dry run 用）
+func (s *SetReleaseTag) releasePlan(repository Repository, selected types.ImageIdentifier, tagResult *TagResult) ReleasePlan {
+	plan := ReleasePlan{
+		DryRun:          true,
+		Repository:      repository.Name,
+		Source:          imageIdString(selected),
+		Changes:         []TagChange{},
+		ImagesLosingTag: []string{},
+	}
+	losing := make(map[string]bool)
+	for _, v := range tagResult.Tags {
+		plan.Changes = append(plan.Changes, TagChange{
+			Tag:        v.Tag,
+			FromDigest: v.PreviousDigest,
+			ToDigest:   tagResult.Digest,
+		})
+		if v.PreviousDigest != "" && v.PreviousDigest != tagResult.Digest && !losing[v.PreviousDigest] {
+			losing[v.PreviousDigest] = true
+			plan.ImagesLosingTag = append(plan.ImagesLosingTag, v.PreviousDigest)
+		}
+	}
+	return plan
+}
+
+// リクエストボディからリリース対象イメージの識別子を取得（tag と digest のどちらか一方のみ指定可）
+func selectedImageId(imageTag ImageTag) (types.ImageIdentifier, error) {
+	tag := aws.ToString(imageTag.Tag)
+	digest := aws.ToString(imageTag.Digest)
+	switch {
+	case tag != "" && digest != "":
+		return types.ImageIdentifier{}, errors.New("tag と digest は同時に指定できません")
+	case tag != "":
+		return types.ImageIdentifier{ImageTag: aws.String(tag)}, nil
+	case digest != "":
+		return types.ImageIdentifier{ImageDigest: aws.String(digest)}, nil
+	}
+	return types.ImageIdentifier{}, errors.New("tag または digest を指定してください")
+}
+
+// 呼び出し元の権限を確認（権限がない場合は 403 を返却）
+func (s *SetReleaseTag) authorize(c *gin.Context, repository Repository, permission Permission) bool {
+	principal, _ := PrincipalFromContext(c)
+	err := s.Policy.Authorize(principal, repository.Name, permission)
+	if err != nil {
+		sendError(c, http.StatusForbidden, fmt.Sprintf("%s", err))
+		return false
+	}
+	return true
+}
+
+// リポジトリ名から対象リポジトリを取得（存在しない場合は 404 を返却）
+func (s *SetReleaseTag) findRepository(c *gin.Context, name string) (Repository, bool) {
+	repository, ok := s.Repositories.Get(name)
+	if !ok {
+		sendError(c, http.StatusNotFound, fmt.Sprintf("リポジトリ（%s）は定義されていません", name))
+	}
+	return repository, ok
+}
+
 // コンテナイメージ一覧の取得
 func (s *SetReleaseTag) GetImages(c *gin.Context) {
-	var result []Image
-	region := strings.Split(s.RepositoryUri, ".")[3]
-	ecrClient, err := EcrClient(region)
+	s.getImages(c, s.Repositories.Default())
+}
+
+// リポジトリ指定でのコンテナイメージ一覧の取得
+func (s *SetReleaseTag) GetRepositoryImages(c *gin.Context, name RepositoryName) {
+	repository, ok := s.findRepository(c, name)
+	if !ok {
+		return
+	}
+	s.getImages(c, repository)
+}
+
+// リリース対象のタグ設定後コンテナイメージ一覧取得
+func (s *SetReleaseTag) PostImages(c *gin.Context, params PostImagesParams) {
+	s.postImages(c, s.Repositories.Default(), params.DryRun != nil && *params.DryRun)
+}
+
+// リポジトリ指定でのリリース対象のタグ設定後コンテナイメージ一覧取得
+func (s *SetReleaseTag) PostRepositoryImages(c *gin.Context, name RepositoryName, params PostRepositoryImagesParams) {
+	repository, ok := s.findRepository(c, name)
+	if !ok {
+		return
+	}
+	s.postImages(c, repository, params.DryRun != nil && *params.DryRun)
+}
+
+// リリースタグのロールバック後コンテナイメージ一覧取得
+func (s *SetReleaseTag) PostImagesRollback(c *gin.Context) {
+	s.postImagesRollback(c, s.Repositories.Default())
+}
+
+// リポジトリ指定でのリリースタグのロールバック後コンテナイメージ一覧取得
+func (s *SetReleaseTag) PostRepositoryImagesRollback(c *gin.Context, name RepositoryName) {
+	repository, ok := s.findRepository(c, name)
+	if !ok {
+		return
+	}
+	s.postImagesRollback(c, repository)
+}
+
+// タグ削除後コンテナイメージ一覧取得
+func (s *SetReleaseTag) DeleteImagesTag(c *gin.Context, tag ImageTagName, params DeleteImagesTagParams) {
+	s.deleteImagesTag(c, s.Repositories.Default(), tag, params.Force != nil && *params.Force)
+}
+
+// リポジトリ指定でのタグ削除後コンテナイメージ一覧取得
+func (s *SetReleaseTag) DeleteRepositoryImagesTag(c *gin.Context, name RepositoryName, tag ImageTagName, params DeleteRepositoryImagesTagParams) {
+	repository, ok := s.findRepository(c, name)
+	if !ok {
+		return
+	}
+	s.deleteImagesTag(c, repository, tag, params.Force != nil && *params.Force)
+}
+
+// コンテナイメージ一覧を返す（キャッシュの有効期間内は ECR を呼び出さない。X-Cache・Age ヘッダーでキャッシュの状態を返す）
+func (s *SetReleaseTag) sendImages(c *gin.Context, ecrClient ECRAPI, repository Repository) {
+	cached, err := s.Images.Get(requestContext(c), repository.Name, func(ctx context.Context) ([]Image, error) {
+		return ImageList(ctx, ecrClient, repository.Uri, s.PageSize, s.MaxImages)
+	})
 	if err != nil {
-		sendError(c, http.StatusInternalServerError, fmt.Sprintf("%s", err))
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	if cached.Hit {
+		c.Header("X-Cache", "HIT")
+	} else {
+		c.Header("X-Cache", "MISS")
+	}
+	c.Header("Age", strconv.Itoa(int(cached.Age.Seconds())))
+	c.JSON(http.StatusOK, cached.Images)
+}
+
+func (s *SetReleaseTag) getImages(c *gin.Context, repository Repository) {
+	if !s.authorize(c, repository, PermissionList) {
 		return
 	}
-	result, err = ImageList(context.TODO(), ecrClient, s.RepositoryUri)
+	ecrClient, err := s.ecrClient(repository)
 	if err != nil {
-		sendError(c, http.StatusInternalServerError, fmt.Sprintf("%s", err))
+		sendServerError(c, fmt.Sprintf("%s", err), err)
 		return
 	}
-	c.JSON(http.StatusOK, result)
+	s.sendImages(c, ecrClient, repository)
 }
 
-// リリース対象のタグ設定後コンテナイメージ一覧取得
-func (s *SetReleaseTag) PostImages(c *gin.Context) {
+func (s *SetReleaseTag) postImages(c *gin.Context, repository Repository, dryRun bool) {
+	if !s.authorize(c, repository, PermissionRelease) {
+		return
+	}
 	var imageTag ImageTag
 	err := c.Bind(&imageTag)
 	if err != nil {
 		sendError(c, http.StatusBadRequest, fmt.Sprintf("パラメーターの形式が誤っています : %s", err))
 		return
 	}
+	selected, err := selectedImageId(imageTag)
+	if err != nil {
+		sendError(c, http.StatusBadRequest, fmt.Sprintf("パラメーターの形式が誤っています : %s", err))
+		return
+	}
+	dryRun = dryRun || aws.ToBool(imageTag.DryRun)
+	if repository.RequireApproval && !dryRun {
+		sendError(c, http.StatusForbidden, fmt.Sprintf("リポジトリ（%s）のリリースには承認が必要です（POST /release-requests で申請してください）", repository.Name))
+		return
+	}
+	if imageTag.ScheduledAt != nil {
+		if dryRun {
+			sendError(c, http.StatusBadRequest, "dry_run と scheduled_at は同時に指定できません")
+			return
+		}
+		if !s.schedulingAvailable(c) || !validScheduledAt(c, *imageTag.ScheduledAt) {
+			return
+		}
+	}
 
-	// リリースタグ設定
-	region := strings.Split(s.RepositoryUri, ".")[3]
-	ecrClient, err := EcrClient(region)
+	// リリースタグ設定（予約・dry run 以外は付け替える前の失敗もリリース履歴に記録）
+	record := Release{
+		Operation:  ReleaseOperationRelease,
+		Repository: repository.Name,
+		SourceTag:  imageIdString(selected),
+		Caller:     caller(c),
+	}
+	ecrClient, err := s.ecrClient(repository)
 	if err != nil {
-		sendError(c, http.StatusInternalServerError, fmt.Sprintf("%s", err))
+		if imageTag.ScheduledAt == nil && !dryRun {
+			record.Tag = s.TagNames[0]
+			s.recordRelease(requestContext(c), record, nil, err)
+		}
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	if imageTag.ScheduledAt != nil || dryRun {
+		tagNames, err := s.releaseTagNames(requestContext(c), ecrClient, repository, imageIdString(selected))
+		if err != nil {
+			sendReleaseError(c, "タグの設定", err)
+			return
+		}
+		if imageTag.ScheduledAt != nil {
+			// 予約の場合は付与するタグを生成できることだけ確認し、実行時に改めて生成する
+			s.scheduleRelease(c, ecrClient, repository, selected, *imageTag.ScheduledAt)
+			return
+		}
+		// dry run の場合は付け替え内容のみ返す（承認が必要なリポジトリでも可）
+		tagResult, err := PlanTag(requestContext(c), ecrClient, repository.Uri, tagNames, selected)
+		if err != nil {
+			sendServerError(c, fmt.Sprintf("タグの設定内容の確認が失敗しました : %s", err), err)
+			return
+		}
+		c.JSON(http.StatusOK, s.releasePlan(repository, selected, tagResult))
 		return
 	}
-	err = SetTag(context.TODO(), ecrClient, s.RepositoryUri, s.TagName, imageTag.Tag)
+	tagResult, err := s.applyReleaseTags(requestContext(c), ecrClient, repository, selected, record)
 	if err != nil {
-		sendError(c, http.StatusInternalServerError, fmt.Sprintf("タグの設定が失敗しました : %s", err))
+		sendReleaseError(c, "タグの設定", err)
 		return
 	}
+	// 既に対象イメージにリリースタグが付いていた場合も成功として返す
+	if tagResult.Unchanged {
+		c.Header("X-Release-Status", "unchanged")
+	} else {
+		c.Header("X-Release-Status", "updated")
+	}
 
 	// タグ設定後のコンテナイメージ一覧取得
-	var result []Image
-	result, err = ImageList(context.TODO(), ecrClient, s.RepositoryUri)
+	s.sendImages(c, ecrClient, repository)
+}
+
+func (s *SetReleaseTag) postImagesRollback(c *gin.Context, repository Repository) {
+	if !s.authorize(c, repository, PermissionRollback) {
+		return
+	}
+	if repository.RequireApproval {
+		// ロールバックもリリースタグを付け替えるため、承認なしでは実行できない
+		sendError(c, http.StatusForbidden, fmt.Sprintf("リポジトリ（%s）のロールバックには承認が必要です（POST /release-requests で戻す先のイメージを申請してください）", repository.Name))
+		return
+	}
+	if s.Releases == nil {
+		sendError(c, http.StatusServiceUnavailable, "リリース履歴を記録していないため、ロールバックは使えません")
+		return
+	}
+	ecrClient, err := s.ecrClient(repository)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+
+	// 直前にリリースタグが付いていたイメージへ戻す（プレースホルダーを含むタグは付け替えない）
+	tagNames := FixedTagNames(s.TagNames)
+	if len(tagNames) == 0 {
+		sendError(c, http.StatusConflict, "付け替えるリリースタグが設定されていないためロールバックできません")
+		return
+	}
+	mutability, err := TagMutability(requestContext(c), ecrClient, repository.Uri)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	if mutability == types.ImageTagMutabilityImmutable {
+		sendError(c, http.StatusConflict, fmt.Sprintf("リポジトリ（%s）のロールバックができません : %s", repository.Name, ErrImmutableTag))
+		return
+	}
+	// 付け替え始めたら呼び出し元が接続を切っても途中で止めない
+	ctx, cancel := mutationContext(requestContext(c))
+	defer cancel()
+	err = s.lockReleases()
+	if err != nil {
+		sendReleaseError(c, "タグのロールバック", err)
+		return
+	}
+	digest, ok, err := s.Releases.RollbackTarget(repository.Name, tagNames[0], s.RollbackDepth)
 	if err != nil {
-		sendError(c, http.StatusInternalServerError, fmt.Sprintf("%s", err))
+		s.releaseLock.Unlock()
+		sendServerError(c, fmt.Sprintf("%s", err), err)
 		return
 	}
+	if !ok {
+		s.releaseLock.Unlock()
+		sendError(c, http.StatusConflict, fmt.Sprintf("リポジトリ（%s）にはロールバックできるリリースがありません", repository.Name))
+		return
+	}
+	tagResult, err := SetTag(ctx, ecrClient, repository.Uri, tagNames, types.ImageIdentifier{
+		ImageDigest: aws.String(digest),
+	})
+	s.invalidateImages(repository, tagResult)
+	s.recordRelease(requestContext(c), Release{
+		Operation:  ReleaseOperationRollback,
+		Repository: repository.Name,
+		Tag:        tagNames[0],
+		Digest:     digest,
+		Caller:     caller(c),
+	}, tagResult, err)
+	s.releaseLock.Unlock()
+	if err != nil {
+		sendReleaseError(c, "タグのロールバック", err)
+		return
+	}
+
+	// ロールバック後のコンテナイメージ一覧取得
+	s.sendImages(c, ecrClient, repository)
+}
+
+func (s *SetReleaseTag) deleteImagesTag(c *gin.Context, repository Repository, tag string, force bool) {
+	if !s.authorize(c, repository, PermissionDeleteTag) {
+		return
+	}
+	if repository.RequireApproval && contains(FixedTagNames(s.TagNames), tag) {
+		// リリースタグを外すとリリース中のイメージが変わるため、承認なしでは実行できない
+		sendError(c, http.StatusForbidden, fmt.Sprintf("承認が必要なリポジトリ（%s）のリリースタグ（%s）は外せません（別のイメージのリリースを POST /release-requests で申請してください）", repository.Name, tag))
+		return
+	}
+	ecrClient, err := s.ecrClient(repository)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+
+	// 呼び出し元が既に接続を切っていればタグを外さない（外し始めたら途中で止めない）
+	if err := requestContext(c).Err(); err != nil {
+		sendServerError(c, "タグの削除を中止しました", err)
+		return
+	}
+	ctx, cancel := mutationContext(requestContext(c))
+	defer cancel()
+
+	// リリースタグを外した場合は、外す前のイメージにロールバックで戻せる（リリース履歴から戻す先を決める）
+	err = s.lockReleases()
+	if err != nil {
+		sendReleaseError(c, "タグの削除", err)
+		return
+	}
+	tagResult, err := RemoveTag(ctx, ecrClient, repository.Uri, tag, force)
+	s.invalidateImages(repository, tagResult)
+	s.recordRelease(requestContext(c), Release{
+		Operation:  ReleaseOperationUntag,
+		Repository: repository.Name,
+		Tag:        tag,
+		Caller:     caller(c),
+	}, tagResult, err)
+	s.releaseLock.Unlock()
+	if errors.Is(err, ErrImageTagNotFound) {
+		sendError(c, http.StatusNotFound, fmt.Sprintf("タグの削除が失敗しました : %s", err))
+		return
+	}
+	if errors.Is(err, ErrLastImageTag) {
+		sendError(c, http.StatusConflict, fmt.Sprintf("タグの削除が失敗しました : %s", err))
+		return
+	}
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("タグの削除が失敗しました : %s", err), err)
+		return
+	}
+
+	// タグ削除後のコンテナイメージ一覧取得
+	s.sendImages(c, ecrClient, repository)
+}
+
+// リポジトリ情報の取得
+func (s *SetReleaseTag) GetRepository(c *gin.Context, name RepositoryName) {
+	repository, ok := s.findRepository(c, name)
+	if !ok || !s.authorize(c, repository, PermissionList) {
+		return
+	}
+	ecrClient, err := s.ecrClient(repository)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	mutability, err := TagMutability(requestContext(c), ecrClient, repository.Uri)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	templates, err := s.releaseTagTemplates(mutability)
+	if err != nil {
+		// リリースできない場合は付与するタグなし
+		templates = []string{}
+	}
+	c.JSON(http.StatusOK, RepositoryInfo{
+		Name:               repository.Name,
+		Uri:                repository.Uri,
+		ImageTagMutability: RepositoryInfoImageTagMutability(mutability),
+		ImmutableStrategy:  s.ImmutableStrategy,
+		Tags:               templates,
+	})
+}
+
+// リリース履歴の取得
+func (s *SetReleaseTag) GetReleases(c *gin.Context, params GetReleasesParams) {
+	if s.Releases == nil {
+		sendError(c, http.StatusServiceUnavailable, "リリース履歴は記録していません")
+		return
+	}
+	var repositoryName string
+	if params.Repository != nil {
+		repository, ok := s.findRepository(c, *params.Repository)
+		if !ok || !s.authorize(c, repository, PermissionList) {
+			return
+		}
+		repositoryName = repository.Name
+	} else {
+		// 全リポジトリの履歴はすべてのリポジトリの list 権限が必要
+		for _, v := range s.Repositories.List() {
+			if !s.authorize(c, v, PermissionList) {
+				return
+			}
+		}
+	}
+	limit := 20
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+	var cursor int64
+	if params.Cursor != nil {
+		cursor = *params.Cursor
+	}
+
+	releases, nextCursor, err := s.Releases.List(repositoryName, cursor, limit)
+	if err != nil {
+		sendServerError(c, fmt.Sprintf("%s", err), err)
+		return
+	}
+	result := ReleaseList{
+		Releases: releases,
+	}
+	if nextCursor > 0 {
+		result.NextCursor = &nextCursor
+	}
 	c.JSON(http.StatusOK, result)
 }
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// タグテンプレートのプレースホルダー
+const (
+	// 日付（YYYYMMDD）
+	TagPlaceholderDate = "{date}"
+	// 時刻（hhmmss）
+	TagPlaceholderTime = "{time}"
+	// 連番（{seq} 以外の部分が一致する既存のタグの番号の最大値 + 1）
+	TagPlaceholderSeq = "{seq}"
+	// リリース対象として指定したタグ（ダイジェスト指定時はダイジェストの先頭 12 桁）
+	TagPlaceholderSource = "{source}"
+)
+
+var tagPlaceholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// タグとして使える文字（プレースホルダー置換後）
+var tagNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,299}$`)
+
+// タグテンプレートの検証
+func ValidateTagTemplates(templates []string) error {
+	if len(templates) == 0 {
+		return fmt.Errorf("付与するタグの指定がありません")
+	}
+	for _, v := range templates {
+		for _, placeholder := range tagPlaceholderPattern.FindAllString(v, -1) {
+			switch placeholder {
+			case TagPlaceholderDate, TagPlaceholderTime, TagPlaceholderSeq, TagPlaceholderSource:
+			default:
+				return fmt.Errorf("タグテンプレート（%s）のプレースホルダー（%s）は使えません", v, placeholder)
+			}
+		}
+		if strings.Count(v, TagPlaceholderSeq) > 1 {
+			return fmt.Errorf("タグテンプレート（%s）に %s は 1 つしか指定できません", v, TagPlaceholderSeq)
+		}
+		// プレースホルダーを仮の値に置き換えてタグとして使えるか確認
+		sample := strings.NewReplacer(TagPlaceholderDate, "20060102", TagPlaceholderTime, "150405", TagPlaceholderSeq, "1", TagPlaceholderSource, "source").Replace(v)
+		if !tagNamePattern.MatchString(sample) {
+			return fmt.Errorf("タグテンプレート（%s）はタグとして使えない形式です", v)
+		}
+	}
+	return nil
+}
+
+// プレースホルダーを含むか
+func IsTagTemplate(template string) bool {
+	return tagPlaceholderPattern.MatchString(template)
+}
+
+// プレースホルダーを含まない（リリースごとに付け替える）タグ
+func FixedTagNames(templates []string) []string {
+	var tagNames []string
+	for _, v := range templates {
+		if !IsTagTemplate(v) {
+			tagNames = append(tagNames, v)
+		}
+	}
+	return tagNames
+}
+
+// {source} に入れる値（ダイジェストは sha256: を除いた先頭 12 桁）
+func tagSource(source string) string {
+	if strings.HasPrefix(source, "sha256:") {
+		digest := strings.TrimPrefix(source, "sha256:")
+		if len(digest) > 12 {
+			digest = digest[:12]
+		}
+		return digest
+	}
+	return source
+}
+
+// タグテンプレートからタグ名を生成（existingTags はリポジトリ内の既存のタグで、{seq} の採番に使う）
+func RenderTagNames(templates []string, now time.Time, source string, existingTags []string) []string {
+	replacer := strings.NewReplacer(
+		TagPlaceholderDate, now.Format("20060102"),
+		TagPlaceholderTime, now.Format("150405"),
+		TagPlaceholderSource, tagSource(source),
+	)
+	var tagNames []string
+	for _, v := range templates {
+		if !strings.Contains(v, TagPlaceholderSeq) {
+			tagNames = append(tagNames, replacer.Replace(v))
+			continue
+		}
+		parts := strings.SplitN(v, TagPlaceholderSeq, 2)
+		prefix := replacer.Replace(parts[0])
+		suffix := replacer.Replace(parts[1])
+		seq := 0
+		for _, tag := range existingTags {
+			if len(tag) <= len(prefix)+len(suffix) || !strings.HasPrefix(tag, prefix) || !strings.HasSuffix(tag, suffix) {
+				continue
+			}
+			n, err := strconv.Atoi(tag[len(prefix) : len(tag)-len(suffix)])
+			if err == nil && n > seq {
+				seq = n
+			}
+		}
+		tagNames = append(tagNames, fmt.Sprintf("%s%d%s", prefix, seq+1, suffix))
+	}
+	return tagNames
+}
+
+// タグテンプレートからタグ名を生成（{seq} を含む場合のみリポジトリ内の既存のタグを取得）
+func ResolveTagNames(ctx context.Context, api ECRAPI, repositoryUri string, templates []string, source string, now time.Time, pageSize int32) ([]string, error) {
+	var existingTags []string
+	for _, v := range templates {
+		if !strings.Contains(v, TagPlaceholderSeq) {
+			continue
+		}
+		uri, err := ParseRepositoryUri(repositoryUri)
+		if err != nil {
+			return nil, err
+		}
+		repositoryName := uri.Name
+		registryId := uri.RegistryId
+		imageDetails, err := EcrDescribeImages(ctx, api, repositoryName, registryId, pageSize, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, detail := range imageDetails {
+			existingTags = append(existingTags, detail.ImageTags...)
+		}
+		break
+	}
+	return RenderTagNames(templates, now, source, existingTags), nil
+}
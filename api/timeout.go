@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/gin-gonic/gin"
+)
+
+// ECR API 1 回あたり（SDK による再試行を含む）のタイムアウトのデフォルト
+const DefaultEcrCallTimeout = 20 * time.Second
+
+// リクエスト・予約リリース 1 件あたりのタイムアウトのデフォルト
+const DefaultOperationTimeout = time.Minute
+
+// 呼び出し元がリクエストを取り消した（接続を切った）場合のステータスコード（nginx の 499 Client Closed Request）
+const StatusClientClosedRequest = 499
+
+// リクエスト全体のタイムアウトを設定する Middleware（0 以下の場合は設定しない）
+func OperationTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// 取り消し・期限を引き継がず、値（ロガーなど）のみを引き継ぐ context
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (d detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (d detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+// 複数のリクエストで共有する処理用の context（最初の呼び出し元が接続を切っても止めない）
+func detachContext(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+// タグの付け替え・削除用の context（呼び出し元が接続を切っても途中で止めない。期限は引き継ぐ）
+func mutationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := detachContext(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	return context.WithCancel(detached)
+}
+
+// ECR API の呼び出しごとにタイムアウトを設定するクライアント
+type timeoutECR struct {
+	api     ECRAPI
+	timeout time.Duration
+}
+
+// ECR クライアントの呼び出しごとにタイムアウトを設定する（0 以下の場合は設定しない）
+func WithEcrCallTimeout(api ECRAPI, timeout time.Duration) ECRAPI {
+	if timeout <= 0 {
+		return api
+	}
+	return timeoutECR{api: api, timeout: timeout}
+}
+
+// 呼び出しのタイムアウトで失敗した場合は、どの呼び出しがタイムアウトしたかをエラーに含める
+func (e timeoutECR) wrapError(ctx context.Context, callCtx context.Context, method string, err error) error {
+	if err == nil || ctx.Err() != nil || !errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("ECR API（%s）の呼び出しが %s 以内に完了しませんでした : %w", method, e.timeout, context.DeadlineExceeded)
+}
+
+func (e timeoutECR) DescribeImages(ctx context.Context, params *ecr.DescribeImagesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
+	callCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	output, err := e.api.DescribeImages(callCtx, params, optFns...)
+	return output, e.wrapError(ctx, callCtx, "DescribeImages", err)
+}
+
+func (e timeoutECR) BatchGetImage(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error) {
+	callCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	output, err := e.api.BatchGetImage(callCtx, params, optFns...)
+	return output, e.wrapError(ctx, callCtx, "BatchGetImage", err)
+}
+
+func (e timeoutECR) PutImage(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error) {
+	callCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	output, err := e.api.PutImage(callCtx, params, optFns...)
+	return output, e.wrapError(ctx, callCtx, "PutImage", err)
+}
+
+func (e timeoutECR) BatchDeleteImage(ctx context.Context, params *ecr.BatchDeleteImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error) {
+	callCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	output, err := e.api.BatchDeleteImage(callCtx, params, optFns...)
+	return output, e.wrapError(ctx, callCtx, "BatchDeleteImage", err)
+}
+
+func (e timeoutECR) DescribeRepositories(ctx context.Context, params *ecr.DescribeRepositoriesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
+	callCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	output, err := e.api.DescribeRepositories(callCtx, params, optFns...)
+	return output, e.wrapError(ctx, callCtx, "DescribeRepositories", err)
+}
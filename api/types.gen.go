@@ -4,14 +4,111 @@
 package api
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/deepmap/oapi-codegen/pkg/runtime"
+)
+
+const (
+	ApiKeyScopes     = "apiKey.Scopes"
+	BearerAuthScopes = "bearerAuth.Scopes"
+)
+
+// Defines values for HealthStatus.
+const (
+	Ok HealthStatus = "ok"
+)
+
+// Defines values for ReadinessStatus.
+const (
+	NotReady ReadinessStatus = "not_ready"
+	Ready    ReadinessStatus = "ready"
+)
+
+// Defines values for ReleaseOperation.
+const (
+	ReleaseOperationRelease  ReleaseOperation = "release"
+	ReleaseOperationRollback ReleaseOperation = "rollback"
+	ReleaseOperationUntag    ReleaseOperation = "untag"
+)
+
+// Defines values for ReleaseResult.
+const (
+	Failure   ReleaseResult = "failure"
+	Success   ReleaseResult = "success"
+	Unchanged ReleaseResult = "unchanged"
+)
+
+// Defines values for ReleaseRequestStatus.
+const (
+	ReleaseRequestStatusApproved ReleaseRequestStatus = "approved"
+	ReleaseRequestStatusExpired  ReleaseRequestStatus = "expired"
+	ReleaseRequestStatusFailed   ReleaseRequestStatus = "failed"
+	ReleaseRequestStatusPending  ReleaseRequestStatus = "pending"
+	ReleaseRequestStatusRejected ReleaseRequestStatus = "rejected"
+)
+
+// Defines values for RepositoryInfoImageTagMutability.
+const (
+	IMMUTABLE RepositoryInfoImageTagMutability = "IMMUTABLE"
+	MUTABLE   RepositoryInfoImageTagMutability = "MUTABLE"
+)
+
+// Defines values for RepositoryInfoImmutableStrategy.
+const (
+	Fail       RepositoryInfoImmutableStrategy = "fail"
+	UniqueTags RepositoryInfoImmutableStrategy = "unique_tags"
+)
+
+// Defines values for ScheduledReleaseStatus.
+const (
+	ScheduledReleaseStatusCancelled ScheduledReleaseStatus = "cancelled"
+	ScheduledReleaseStatusFailed    ScheduledReleaseStatus = "failed"
+	ScheduledReleaseStatusPending   ScheduledReleaseStatus = "pending"
+	ScheduledReleaseStatusRunning   ScheduledReleaseStatus = "running"
+	ScheduledReleaseStatusSucceeded ScheduledReleaseStatus = "succeeded"
+)
+
+// Defines values for GetReleaseRequestsParamsStatus.
+const (
+	GetReleaseRequestsParamsStatusApproved GetReleaseRequestsParamsStatus = "approved"
+	GetReleaseRequestsParamsStatusExpired  GetReleaseRequestsParamsStatus = "expired"
+	GetReleaseRequestsParamsStatusFailed   GetReleaseRequestsParamsStatus = "failed"
+	GetReleaseRequestsParamsStatusPending  GetReleaseRequestsParamsStatus = "pending"
+	GetReleaseRequestsParamsStatusRejected GetReleaseRequestsParamsStatus = "rejected"
+)
+
+// Defines values for GetScheduledReleasesParamsStatus.
+const (
+	GetScheduledReleasesParamsStatusCancelled GetScheduledReleasesParamsStatus = "cancelled"
+	GetScheduledReleasesParamsStatusFailed    GetScheduledReleasesParamsStatus = "failed"
+	GetScheduledReleasesParamsStatusPending   GetScheduledReleasesParamsStatus = "pending"
+	GetScheduledReleasesParamsStatusRunning   GetScheduledReleasesParamsStatus = "running"
+	GetScheduledReleasesParamsStatusSucceeded GetScheduledReleasesParamsStatus = "succeeded"
 )
 
 // Error エラーメッセージモデル
 type Error struct {
-	Message string `json:"message"`
+	// AppliedTags 付与できたタグ（複数タグのうち一部の付与に失敗した場合のみ）
+	AppliedTags *[]string `json:"applied_tags,omitempty"`
+
+	// FailedTags 付与できなかったタグ（複数タグのうち一部の付与に失敗した場合のみ）
+	FailedTags *[]string `json:"failed_tags,omitempty"`
+	Message    string    `json:"message"`
+
+	// RequestId リクエスト ID（レスポンスヘッダー X-Request-Id と同じ）
+	RequestId *string `json:"request_id,omitempty"`
+}
+
+// Health 死活監視モデル
+type Health struct {
+	Status HealthStatus `json:"status"`
 }
 
+// HealthStatus defines model for Health.Status.
+type HealthStatus string
+
 // Image コンテナイメージモデル
 type Image struct {
 	Digest         string    `json:"digest"`
@@ -21,19 +118,384 @@ type Image struct {
 	Tags           []string  `json:"tags"`
 }
 
-// ImageTag defines model for ImageTag.
+// ImageTag リリース対象イメージの指定（tag と digest のどちらか一方のみ指定）
 type ImageTag struct {
-	Tag string `json:"tag"`
+	Digest *string `json:"digest,omitempty"`
+
+	// DryRun true のときはタグを付け替えずに実行計画を返す（クエリパラメーター dry_run と同じ）
+	DryRun *bool `json:"dry_run,omitempty"`
+
+	// ScheduledAt 指定した日時にリリースする（予約リリースを作成して 202 を返す。dry_run とは同時に指定できない）
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	Tag         *string    `json:"tag,omitempty"`
+}
+
+// Images コンテナイメージ一覧モデル
+type Images = []Image
+
+// Readiness リクエストを受け付けられるかの確認結果モデル
+type Readiness struct {
+	// CheckedAt 確認した日時（キャッシュした結果の場合は前回の確認日時）
+	CheckedAt    time.Time             `json:"checked_at"`
+	Repositories []RepositoryReadiness `json:"repositories"`
+	Status       ReadinessStatus       `json:"status"`
+}
+
+// ReadinessStatus defines model for Readiness.Status.
+type ReadinessStatus string
+
+// Release リリース履歴モデル
+type Release struct {
+	Caller         string           `json:"caller"`
+	Digest         string           `json:"digest"`
+	Id             int64            `json:"id"`
+	Message        *string          `json:"message,omitempty"`
+	Operation      ReleaseOperation `json:"operation"`
+	PreviousDigest string           `json:"previous_digest"`
+
+	// ReleaseRequestId 承認したリリース申請の ID（申請によるリリースのみ）
+	ReleaseRequestId *int64        `json:"release_request_id,omitempty"`
+	ReleasedAt       time.Time     `json:"released_at"`
+	Repository       string        `json:"repository"`
+	Result           ReleaseResult `json:"result"`
+
+	// ScheduledReleaseId 予約リリースの ID（予約リリースのみ）
+	ScheduledReleaseId *int64 `json:"scheduled_release_id,omitempty"`
+	SourceTag          string `json:"source_tag"`
+	Tag                string `json:"tag"`
+
+	// Tags 付与したすべてのタグ（テンプレートから生成したタグを含む）
+	Tags *[]string `json:"tags,omitempty"`
+}
+
+// ReleaseOperation defines model for Release.Operation.
+type ReleaseOperation string
+
+// ReleaseResult defines model for Release.Result.
+type ReleaseResult string
+
+// ReleaseList リリース履歴一覧モデル
+type ReleaseList struct {
+	// NextCursor 次のページを取得するときの cursor（最後のページでは省略）
+	NextCursor *int64    `json:"next_cursor,omitempty"`
+	Releases   []Release `json:"releases"`
+}
+
+// ReleasePlan リリース実行計画モデル（dry_run 時のレスポンス）
+type ReleasePlan struct {
+	Changes []TagChange `json:"changes"`
+	DryRun  bool        `json:"dry_run"`
+
+	// ImagesLosingTag タグが外れるイメージのダイジェスト
+	ImagesLosingTag []string `json:"images_losing_tag"`
+	Repository      string   `json:"repository"`
+
+	// Source リクエストで指定したタグまたはダイジェスト
+	Source string `json:"source"`
+}
+
+// ReleaseRequest リリース申請モデル
+type ReleaseRequest struct {
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	DecidedBy *string    `json:"decided_by,omitempty"`
+
+	// Digest 申請時に対象イメージが持っていたダイジェスト
+	Digest      string    `json:"digest"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Id          int64     `json:"id"`
+	Message     *string   `json:"message,omitempty"`
+	Repository  string    `json:"repository"`
+	RequestedAt time.Time `json:"requested_at"`
+	RequestedBy string    `json:"requested_by"`
+
+	// ScheduledAt 承認後にリリースする日時
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+
+	// ScheduledReleaseId 承認時に作成した予約リリースの ID
+	ScheduledReleaseId *int64 `json:"scheduled_release_id,omitempty"`
+
+	// Source 申請で指定したタグまたはダイジェスト
+	Source string               `json:"source"`
+	Status ReleaseRequestStatus `json:"status"`
+
+	// Tags 承認時に付与したタグ
+	Tags *[]string `json:"tags,omitempty"`
+}
+
+// ReleaseRequestStatus defines model for ReleaseRequest.Status.
+type ReleaseRequestStatus string
+
+// ReleaseRequestInput リリース申請の指定（tag と digest のどちらか一方のみ指定）
+type ReleaseRequestInput struct {
+	Digest *string `json:"digest,omitempty"`
+
+	// Repository 設定ファイルで定義したリポジトリ名（省略時はデフォルトのリポジトリ）
+	Repository *string `json:"repository,omitempty"`
+
+	// ScheduledAt 承認後、指定した日時にリリースする（承認時に予約リリースを作成）
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	Tag         *string    `json:"tag,omitempty"`
+}
+
+// ReleaseRequestList リリース申請一覧モデル
+type ReleaseRequestList struct {
+	ReleaseRequests []ReleaseRequest `json:"release_requests"`
+}
+
+// RepositoryInfo リポジトリ情報モデル
+type RepositoryInfo struct {
+	// ImageTagMutability ECR リポジトリのタグの変更可否
+	ImageTagMutability RepositoryInfoImageTagMutability `json:"image_tag_mutability"`
+
+	// ImmutableStrategy タグが変更不可のリポジトリでのリリース方法（fail は 409 を返す、unique_tags はプレースホルダーを含むタグのみ付与）
+	ImmutableStrategy RepositoryInfoImmutableStrategy `json:"immutable_strategy"`
+	Name              string                          `json:"name"`
+
+	// Tags リリース時に付与するタグのテンプレート（タグが変更不可でリリースできない場合は空）
+	Tags []string `json:"tags"`
+	Uri  string   `json:"uri"`
+}
+
+// RepositoryInfoImageTagMutability ECR リポジトリのタグの変更可否
+type RepositoryInfoImageTagMutability string
+
+// RepositoryInfoImmutableStrategy タグが変更不可のリポジトリでのリリース方法（fail は 409 を返す、unique_tags はプレースホルダーを含むタグのみ付与）
+type RepositoryInfoImmutableStrategy string
+
+// RepositoryReadiness リポジトリごとの確認結果モデル
+type RepositoryReadiness struct {
+	// Message 確認に失敗した理由
+	Message *string `json:"message,omitempty"`
+	Name    string  `json:"name"`
+	Ready   bool    `json:"ready"`
 }
 
+// ScheduledRelease 予約リリースモデル
+type ScheduledRelease struct {
+	CancelledBy *string   `json:"cancelled_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedBy   string    `json:"created_by"`
+
+	// Digest 予約時に対象イメージが持っていたダイジェスト（このダイジェストのイメージをリリースする）
+	Digest     string     `json:"digest"`
+	ExecutedAt *time.Time `json:"executed_at,omitempty"`
+	Id         int64      `json:"id"`
+	Message    *string    `json:"message,omitempty"`
+
+	// ReleaseRequestId 承認したリリース申請の ID（申請から作成した予約のみ）
+	ReleaseRequestId *int64    `json:"release_request_id,omitempty"`
+	Repository       string    `json:"repository"`
+	ScheduledAt      time.Time `json:"scheduled_at"`
+
+	// Source 指定したタグまたはダイジェスト
+	Source string                 `json:"source"`
+	Status ScheduledReleaseStatus `json:"status"`
+
+	// Tags 付与したタグ
+	Tags *[]string `json:"tags,omitempty"`
+}
+
+// ScheduledReleaseStatus defines model for ScheduledRelease.Status.
+type ScheduledReleaseStatus string
+
+// ScheduledReleaseList 予約リリース一覧モデル
+type ScheduledReleaseList struct {
+	ScheduledReleases []ScheduledRelease `json:"scheduled_releases"`
+}
+
+// TagChange タグ付け替え内容モデル
+type TagChange struct {
+	// FromDigest 現在タグが付いているイメージのダイジェスト（タグが付いたイメージがなければ空）
+	FromDigest string `json:"from_digest"`
+	Tag        string `json:"tag"`
+
+	// ToDigest タグを付けるイメージのダイジェスト
+	ToDigest string `json:"to_digest"`
+}
+
+// DryRun defines model for dryRun.
+type DryRun = bool
+
+// Force defines model for force.
+type Force = bool
+
+// ImageTagName defines model for imageTagName.
+type ImageTagName = string
+
+// ReleaseRequestId defines model for releaseRequestId.
+type ReleaseRequestId = int64
+
+// RepositoryName defines model for repositoryName.
+type RepositoryName = string
+
+// ScheduledReleaseId defines model for scheduledReleaseId.
+type ScheduledReleaseId = int64
+
 // ErrorResponse エラーメッセージモデル
 type ErrorResponse = Error
 
 // ImagesResponse defines model for imagesResponse.
 type ImagesResponse = []Image
 
-// ImagesRequest defines model for imagesRequest.
+// ReadinessResponse リクエストを受け付けられるかの確認結果モデル
+type ReadinessResponse = Readiness
+
+// ReleaseRequestResponse リリース申請モデル
+type ReleaseRequestResponse = ReleaseRequest
+
+// ReleaseRequestsResponse リリース申請一覧モデル
+type ReleaseRequestsResponse = ReleaseRequestList
+
+// ReleaseResponse defines model for releaseResponse.
+type ReleaseResponse struct {
+	union json.RawMessage
+}
+
+// ReleasesResponse リリース履歴一覧モデル
+type ReleasesResponse = ReleaseList
+
+// RepositoryResponse リポジトリ情報モデル
+type RepositoryResponse = RepositoryInfo
+
+// ScheduledReleaseResponse 予約リリースモデル
+type ScheduledReleaseResponse = ScheduledRelease
+
+// ScheduledReleasesResponse 予約リリース一覧モデル
+type ScheduledReleasesResponse = ScheduledReleaseList
+
+// ImagesRequest リリース対象イメージの指定（tag と digest のどちらか一方のみ指定）
 type ImagesRequest = ImageTag
 
+// ReleaseRequestRequest リリース申請の指定（tag と digest のどちらか一方のみ指定）
+type ReleaseRequestRequest = ReleaseRequestInput
+
+// PostImagesParams defines parameters for PostImages.
+type PostImagesParams struct {
+	// DryRun true のときはタグを付け替えずに実行計画を返す
+	DryRun *DryRun `form:"dry_run,omitempty" json:"dry_run,omitempty"`
+}
+
+// DeleteImagesTagParams defines parameters for DeleteImagesTag.
+type DeleteImagesTagParams struct {
+	// Force イメージの最後のタグでも外す（タグのなくなったイメージは ECR から削除される）
+	Force *Force `form:"force,omitempty" json:"force,omitempty"`
+}
+
+// GetReleaseRequestsParams defines parameters for GetReleaseRequests.
+type GetReleaseRequestsParams struct {
+	// Repository 設定ファイルで定義したリポジトリ名（省略時は全リポジトリ）
+	Repository *string `form:"repository,omitempty" json:"repository,omitempty"`
+
+	// Status 申請の状態（省略時はすべて）
+	Status *GetReleaseRequestsParamsStatus `form:"status,omitempty" json:"status,omitempty"`
+}
+
+// GetReleaseRequestsParamsStatus defines parameters for GetReleaseRequests.
+type GetReleaseRequestsParamsStatus string
+
+// GetReleasesParams defines parameters for GetReleases.
+type GetReleasesParams struct {
+	// Repository 設定ファイルで定義したリポジトリ名（省略時は全リポジトリ）
+	Repository *string `form:"repository,omitempty" json:"repository,omitempty"`
+
+	// Limit 1 ページあたりの取得件数
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Cursor 前のページの next_cursor（この ID より古い履歴を取得）
+	Cursor *int64 `form:"cursor,omitempty" json:"cursor,omitempty"`
+}
+
+// PostRepositoryImagesParams defines parameters for PostRepositoryImages.
+type PostRepositoryImagesParams struct {
+	// DryRun true のときはタグを付け替えずに実行計画を返す
+	DryRun *DryRun `form:"dry_run,omitempty" json:"dry_run,omitempty"`
+}
+
+// DeleteRepositoryImagesTagParams defines parameters for DeleteRepositoryImagesTag.
+type DeleteRepositoryImagesTagParams struct {
+	// Force イメージの最後のタグでも外す（タグのなくなったイメージは ECR から削除される）
+	Force *Force `form:"force,omitempty" json:"force,omitempty"`
+}
+
+// GetScheduledReleasesParams defines parameters for GetScheduledReleases.
+type GetScheduledReleasesParams struct {
+	// Repository 設定ファイルで定義したリポジトリ名（省略時は全リポジトリ）
+	Repository *string `form:"repository,omitempty" json:"repository,omitempty"`
+
+	// Status 予約の状態（省略時はすべて）
+	Status *GetScheduledReleasesParamsStatus `form:"status,omitempty" json:"status,omitempty"`
+}
+
+// GetScheduledReleasesParamsStatus defines parameters for GetScheduledReleases.
+type GetScheduledReleasesParamsStatus string
+
 // PostImagesJSONRequestBody defines body for PostImages for application/json ContentType.
 type PostImagesJSONRequestBody = ImageTag
+
+// PostReleaseRequestsJSONRequestBody defines body for PostReleaseRequests for application/json ContentType.
+type PostReleaseRequestsJSONRequestBody = ReleaseRequestInput
+
+// PostRepositoryImagesJSONRequestBody defines body for PostRepositoryImages for application/json ContentType.
+type PostRepositoryImagesJSONRequestBody = ImageTag
+
+// AsImages returns the union data inside the ReleaseResponse as a Images
+func (t ReleaseResponse) AsImages() (Images, error) {
+	var body Images
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromImages overwrites any union data inside the ReleaseResponse as the provided Images
+func (t *ReleaseResponse) FromImages(v Images) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergeImages performs a merge with any union data inside the ReleaseResponse, using the provided Images
+func (t *ReleaseResponse) MergeImages(v Images) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JsonMerge(b, t.union)
+	t.union = merged
+	return err
+}
+
+// AsReleasePlan returns the union data inside the ReleaseResponse as a ReleasePlan
+func (t ReleaseResponse) AsReleasePlan() (ReleasePlan, error) {
+	var body ReleasePlan
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromReleasePlan overwrites any union data inside the ReleaseResponse as the provided ReleasePlan
+func (t *ReleaseResponse) FromReleasePlan(v ReleasePlan) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergeReleasePlan performs a merge with any union data inside the ReleaseResponse, using the provided ReleasePlan
+func (t *ReleaseResponse) MergeReleasePlan(v ReleasePlan) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JsonMerge(b, t.union)
+	t.union = merged
+	return err
+}
+
+func (t ReleaseResponse) MarshalJSON() ([]byte, error) {
+	b, err := t.union.MarshalJSON()
+	return b, err
+}
+
+func (t *ReleaseResponse) UnmarshalJSON(b []byte) error {
+	err := t.union.UnmarshalJSON(b)
+	return err
+}
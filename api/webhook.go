@@ -0,0 +1,318 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 通知の形式
+type WebhookFormat string
+
+const (
+	// イベントをそのまま JSON で送る
+	WebhookFormatJson WebhookFormat = "json"
+	// Slack の Incoming Webhook 形式（text）で送る
+	WebhookFormatSlack WebhookFormat = "slack"
+)
+
+// 署名（HMAC-SHA256）を付けるヘッダー
+const WebhookSignatureHeader = "X-Signature-256"
+
+const (
+	// 送信を試みる回数（デフォルト）
+	DefaultWebhookMaxAttempts = 5
+	// 再送までの待ち時間（デフォルト。再送ごとに 2 倍にする）
+	DefaultWebhookRetryBackoff = time.Second
+	// 再送までの待ち時間の上限
+	webhookMaxBackoff = time.Minute
+	// 1 回の送信のタイムアウト
+	webhookTimeout = 10 * time.Second
+	// 送信待ちのイベント数の上限（超えた場合は通知しない）
+	webhookQueueSize = 100
+)
+
+// 設定ファイル（Webhook の通知先）
+type WebhookConfig struct {
+	Url string `yaml:"url"`
+	// 通知の形式（json または slack、省略時は json）
+	Format WebhookFormat `yaml:"format"`
+	// 署名に使う鍵（省略時は署名しない）
+	Secret string `yaml:"secret"`
+	// 送信を試みる回数（省略時は 5）
+	MaxAttempts int `yaml:"max_attempts"`
+	// 最初の再送までの待ち時間（省略時は 1s）
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+}
+
+// 通知するイベント（リリース履歴と同じタイミングで通知する）
+type WebhookEvent struct {
+	Operation      ReleaseOperation `json:"operation"`
+	Result         ReleaseResult    `json:"result"`
+	Repository     string           `json:"repository"`
+	Tag            string           `json:"tag"`
+	Tags           []string         `json:"tags,omitempty"`
+	Source         string           `json:"source,omitempty"`
+	PreviousDigest string           `json:"previous_digest"`
+	Digest         string           `json:"digest"`
+	Actor          string           `json:"actor"`
+	Timestamp      time.Time        `json:"timestamp"`
+	Message        string           `json:"message,omitempty"`
+	// リリース履歴の ID（リリース履歴を記録していない場合は省略）
+	ReleaseId int64 `json:"release_id,omitempty"`
+}
+
+// リリース履歴から通知するイベントを生成
+func NewWebhookEvent(record Release) WebhookEvent {
+	event := WebhookEvent{
+		Operation:      record.Operation,
+		Result:         record.Result,
+		Repository:     record.Repository,
+		Tag:            record.Tag,
+		Source:         record.SourceTag,
+		PreviousDigest: record.PreviousDigest,
+		Digest:         record.Digest,
+		Actor:          record.Caller,
+		Timestamp:      record.ReleasedAt,
+		ReleaseId:      record.Id,
+	}
+	if record.Tags != nil {
+		event.Tags = *record.Tags
+	}
+	if record.Message != nil {
+		event.Message = *record.Message
+	}
+	return event
+}
+
+// 通知先ごとの送信待ちのイベント
+type webhook struct {
+	cfg   WebhookConfig
+	queue chan WebhookEvent
+}
+
+// Webhook の通知（通知先ごとにバックグラウンドで送信し、呼び出し元は待たない）
+type Notifier struct {
+	client   *http.Client
+	webhooks []*webhook
+	mu       sync.Mutex
+	closed   bool
+	// 停止時に再送の待ちを打ち切る
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Webhook の通知の生成（通知先がなければ nil を返し、通知しない）
+func NewNotifier(configs []WebhookConfig) (*Notifier, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	n := &Notifier{
+		client: &http.Client{Timeout: webhookTimeout},
+		stop:   make(chan struct{}),
+	}
+	errs := validateWebhooks(configs)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	for _, v := range configs {
+		if v.Format == "" {
+			v.Format = WebhookFormatJson
+		}
+		if v.MaxAttempts == 0 {
+			v.MaxAttempts = DefaultWebhookMaxAttempts
+		}
+		if v.RetryBackoff == 0 {
+			v.RetryBackoff = DefaultWebhookRetryBackoff
+		}
+		n.webhooks = append(n.webhooks, &webhook{
+			cfg:   v,
+			queue: make(chan WebhookEvent, webhookQueueSize),
+		})
+	}
+	for _, w := range n.webhooks {
+		n.wg.Add(1)
+		go n.run(w)
+	}
+	return n, nil
+}
+
+// Webhook の通知先の確認（見つかったすべての誤りを返す）
+func validateWebhooks(configs []WebhookConfig) []error {
+	var errs []error
+	for i, v := range configs {
+		u, err := url.Parse(v.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("Webhook（%d 番目）の url（%s）は http または https の URL を指定してください", i+1, v.Url))
+		}
+		switch v.Format {
+		case "", WebhookFormatJson, WebhookFormatSlack:
+		default:
+			errs = append(errs, fmt.Errorf("Webhook（%d 番目）の format（%s）は %s または %s を指定してください", i+1, v.Format, WebhookFormatJson, WebhookFormatSlack))
+		}
+		if v.MaxAttempts < 0 {
+			errs = append(errs, fmt.Errorf("Webhook（%d 番目）の max_attempts（%d）は正の値を指定してください", i+1, v.MaxAttempts))
+		}
+		if v.RetryBackoff < 0 {
+			errs = append(errs, fmt.Errorf("Webhook（%d 番目）の retry_backoff（%s）は正の値を指定してください", i+1, v.RetryBackoff))
+		}
+	}
+	return errs
+}
+
+// イベントを送信待ちに追加（送信待ちが上限を超えた通知先には通知しない）
+func (n *Notifier) Notify(event WebhookEvent) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	for _, w := range n.webhooks {
+		select {
+		case w.queue <- event:
+		default:
+			zap.L().Warn("webhook queue is full, event dropped", zap.String("url", w.cfg.Url), zap.String("repository", event.Repository), zap.String("tag", event.Tag))
+		}
+	}
+}
+
+// 通知を停止（送信待ちのイベントは 1 回ずつ送信し、再送はしない）
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return
+	}
+	n.closed = true
+	close(n.stop)
+	for _, w := range n.webhooks {
+		close(w.queue)
+	}
+	n.mu.Unlock()
+	n.wg.Wait()
+}
+
+func (n *Notifier) run(w *webhook) {
+	defer n.wg.Done()
+	for event := range w.queue {
+		n.deliver(w, event)
+	}
+}
+
+// 失敗した場合は待ち時間を 2 倍にしながら再送
+func (n *Notifier) deliver(w *webhook, event WebhookEvent) {
+	body, err := WebhookPayload(w.cfg.Format, event)
+	if err != nil {
+		zap.L().Error("failed to build webhook payload", zap.String("url", w.cfg.Url), zap.Error(err))
+		return
+	}
+	backoff := w.cfg.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(w, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= w.cfg.MaxAttempts {
+			zap.L().Error("webhook delivery failed", zap.String("url", w.cfg.Url), zap.Int("attempt", attempt), zap.Error(err))
+			return
+		}
+		select {
+		case <-n.stop:
+			zap.L().Error("webhook delivery failed, not retrying during shutdown", zap.String("url", w.cfg.Url), zap.Int("attempt", attempt), zap.Error(err))
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > webhookMaxBackoff {
+			backoff = webhookMaxBackoff
+		}
+	}
+}
+
+// 1 回送信（通信エラー・429・5xx の場合は再送する）
+func (n *Notifier) post(w *webhook, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.cfg.Url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.cfg.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(w.cfg.Secret, body))
+	}
+	res, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+	retry := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+	return retry, fmt.Errorf("ステータスコード %d", res.StatusCode)
+}
+
+// 通知内容の署名（sha256=HMAC-SHA256 の 16 進数）
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// 通知内容を生成
+func WebhookPayload(format WebhookFormat, event WebhookEvent) ([]byte, error) {
+	if format == WebhookFormatSlack {
+		return json.Marshal(map[string]string{
+			"text": slackText(event),
+		})
+	}
+	return json.Marshal(event)
+}
+
+// ダイジェストを短縮（sha256: と先頭 12 文字）
+func shortDigest(digest string) string {
+	if digest == "" {
+		return "なし"
+	}
+	if len(digest) > len("sha256:")+12 {
+		return digest[:len("sha256:")+12]
+	}
+	return digest
+}
+
+// Slack に送るメッセージ
+func slackText(event WebhookEvent) string {
+	operation := map[ReleaseOperation]string{
+		ReleaseOperationRelease:  "リリース",
+		ReleaseOperationRollback: "ロールバック",
+		ReleaseOperationUntag:    "タグ削除",
+	}[event.Operation]
+	var icon, result string
+	switch event.Result {
+	case Success:
+		icon, result = ":white_check_mark:", "成功"
+	case Unchanged:
+		icon, result = ":information_source:", "変更なし"
+	default:
+		icon, result = ":x:", "失敗"
+	}
+	text := fmt.Sprintf("%s *%s%s* `%s` のタグ `%s`\n`%s` → `%s`（実行者 : %s）", icon, operation, result, event.Repository, event.Tag, shortDigest(event.PreviousDigest), shortDigest(event.Digest), event.Actor)
+	if event.Message != "" {
+		text += "\n" + event.Message
+	}
+	return text
+}
@@ -1,59 +1,335 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/gin-gonic/gin"
 
 	middleware "github.com/deepmap/oapi-codegen/pkg/gin-middleware"
 	"github.com/hmatsu47/set-release-tag-api/api"
+	"go.uber.org/zap"
 )
 
-func NewGinSetReleaseTagServer(setReleaseTag *api.SetReleaseTag, port int) *http.Server {
+// HTTP サーバーの設定（0 の項目は制限しない）
+type ServerConfig = api.ServerConfig
+
+func NewGinSetReleaseTagServer(setReleaseTag *api.SetReleaseTag, authenticator *api.Authenticator, serverConfig ServerConfig) *http.Server {
 	swagger, err := api.GetSwagger()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Swagger specの読み取りに失敗しました\n: %s", err)
-		os.Exit(1)
+		zap.L().Fatal("failed to load swagger spec", zap.Error(err))
 	}
 
 	// Swagger Document 非公開
 	swagger.Servers = nil
 
-	// Gin Router 設定
-	r := gin.Default()
+	// Gin Router 設定（リクエスト ID 付きのアクセスログ・panic 時のログ出力・リクエスト全体のタイムアウト）
+	r := gin.New()
+	r.Use(api.RequestLogger(zap.L()), api.Recovery(), api.MaxBodySize(serverConfig.MaxBodySize), api.OperationTimeout(setReleaseTag.OperationTimeout))
 
-	// HTTP Request の Validation 設定
-	r.Use(middleware.OapiRequestValidator(swagger))
+	// 認証・Validation でエラーになったリクエストも含めてメトリクスを記録
+	if setReleaseTag.Metrics != nil {
+		r.Use(setReleaseTag.Metrics.Middleware(api.OperationNames(swagger)))
+	}
+
+	// HTTP Request の Validation・認証設定
+	r.Use(middleware.OapiRequestValidatorWithOptions(swagger, &middleware.Options{
+		ErrorHandler: api.ValidationErrorHandler,
+		Options: openapi3filter.Options{
+			AuthenticationFunc: authenticator.AuthenticationFunc(),
+		},
+	}))
 
 	// Handler 実装
 	r = api.RegisterHandlers(r, setReleaseTag)
 
 	s := &http.Server{
-		Handler: r,
-		Addr:    fmt.Sprintf("0.0.0.0:%d", port),
+		Handler:           r,
+		Addr:              fmt.Sprintf("0.0.0.0:%d", serverConfig.Port),
+		ReadHeaderTimeout: serverConfig.ReadTimeout,
+		ReadTimeout:       serverConfig.ReadTimeout,
+		WriteTimeout:      serverConfig.WriteTimeout,
+		IdleTimeout:       serverConfig.IdleTimeout,
 	}
 	return s
 }
 
+// 設定を確認するだけのサブコマンド
+const validateConfigCommand = "validate-config"
+
+// コマンドライン
+type commandLine struct {
+	// validate-config サブコマンドを指定した
+	validateOnly bool
+	configPath   string
+	// コマンドラインパラメータ（設定ファイル・環境変数の読み込み後に解析し直して上書きする）
+	args []string
+}
+
+// コマンドラインパラメータの定義（デフォルト値は cfg の値。指定したパラメータのみ cfg を上書きする）
+func defineFlags(fs *flag.FlagSet, cfg *api.Config, configPath *string) {
+	fs.StringVar(configPath, "config", "", "Path to config file (YAML)")
+	fs.IntVar(&cfg.Server.Port, "port", cfg.Server.Port, "Port for API server")
+	fs.IntVar(&cfg.Images.PageSize, "page-size", cfg.Images.PageSize, "Page size for ECR DescribeImages (1-1000)")
+	fs.IntVar(&cfg.Images.MaxImages, "max-images", cfg.Images.MaxImages, "Maximum number of images to list (0 = unlimited)")
+	fs.IntVar(&cfg.History.RollbackDepth, "rollback-depth", cfg.History.RollbackDepth, "Number of releases that can be rolled back in a row per repository")
+	fs.StringVar(&cfg.History.Path, "history-db", cfg.History.Path, "Path to release history database file")
+	fs.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "Log format (json or console)")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "Log level (debug, info, warn or error)")
+	fs.DurationVar(&cfg.ScheduleInterval, "schedule-interval", cfg.ScheduleInterval, "Interval for checking scheduled releases")
+	fs.DurationVar(&cfg.Server.ReadTimeout, "read-timeout", cfg.Server.ReadTimeout, "Timeout for reading a request (0 = no timeout)")
+	fs.DurationVar(&cfg.Server.WriteTimeout, "write-timeout", cfg.Server.WriteTimeout, "Timeout for writing a response (0 = no timeout)")
+	fs.DurationVar(&cfg.Server.IdleTimeout, "idle-timeout", cfg.Server.IdleTimeout, "Timeout for idle keep-alive connections (0 = no timeout)")
+	fs.Int64Var(&cfg.Server.MaxBodySize, "max-body-size", cfg.Server.MaxBodySize, "Maximum request body size in bytes (0 = unlimited)")
+	fs.DurationVar(&cfg.Aws.EcrCallTimeout, "ecr-call-timeout", cfg.Aws.EcrCallTimeout, "Timeout for each ECR API call including retries (0 = no timeout)")
+	fs.DurationVar(&cfg.Server.OperationTimeout, "operation-timeout", cfg.Server.OperationTimeout, "Timeout for each request and scheduled release (0 = no timeout)")
+	fs.DurationVar(&cfg.Images.CacheTtl, "image-cache-ttl", cfg.Images.CacheTtl, "Cache duration for image lists (0 = no cache)")
+	fs.DurationVar(&cfg.Server.ReadinessTtl, "readiness-ttl", cfg.Server.ReadinessTtl, "Cache duration for the /readyz result")
+	fs.DurationVar(&cfg.Server.ShutdownTimeout, "shutdown-timeout", cfg.Server.ShutdownTimeout, "Drain period for in-flight requests on SIGTERM/SIGINT (0 waits until they finish)")
+	fs.StringVar(&cfg.Aws.Region, "aws-region", cfg.Aws.Region, "AWS region for loading credentials (ECR uses the region in each repository URI)")
+	fs.StringVar(&cfg.Aws.Profile, "aws-profile", cfg.Aws.Profile, "AWS shared config profile")
+}
+
+// コマンドラインパラメータの解析（validate-config サブコマンドの前後どちらのパラメータも解析し、サブコマンドの指定有無を返す）
+func parseFlags(fs *flag.FlagSet, args []string) (bool, error) {
+	err := fs.Parse(args)
+	if err != nil || fs.Arg(0) != validateConfigCommand {
+		return false, err
+	}
+	return true, fs.Parse(fs.Args()[1:])
+}
+
+// コマンドラインの解析（誤りがあれば output に使い方を出力する）
+func parseCommandLine(args []string, output io.Writer) (*commandLine, error) {
+	fs := flag.NewFlagSet("set-release-tag-api", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(output, "Usage: set-release-tag-api [%s] [flags] [repository-uri [tag]]\n", validateConfigCommand)
+		fs.PrintDefaults()
+	}
+	cmd := &commandLine{args: args}
+	defineFlags(fs, api.DefaultConfig(), &cmd.configPath)
+	validateOnly, err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	cmd.validateOnly = validateOnly
+	return cmd, nil
+}
+
+// 設定の読み込み（デフォルト値・設定ファイル・環境変数・コマンドラインパラメータの順に上書きし、誤りがあればすべてを返す）
+func (cmd *commandLine) loadConfig(lookupEnv func(key string) (string, bool)) (*api.Config, error) {
+	cfg, err := api.ReadConfig(cmd.configPath, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	fs := flag.NewFlagSet("set-release-tag-api", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var configPath string
+	defineFlags(fs, cfg, &configPath)
+	_, err = parseFlags(fs, cmd.args)
+	if err != nil {
+		return nil, err
+	}
+	// 設定ファイルにリポジトリの指定がない場合は、リポジトリ URI・付与するタグをコマンドラインパラメータで取得
+	if len(cfg.Repositories) == 0 && fs.Arg(0) != "" {
+		cfg.DefaultRepository = "default"
+		cfg.Repositories = []api.RepositoryConfig{
+			{
+				Name: "default",
+				Uri:  fs.Arg(0),
+			},
+		}
+		if fs.Arg(1) != "" {
+			cfg.TagName = fs.Arg(1)
+			cfg.Tags = nil
+		}
+	}
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validate-config サブコマンド（誤りがなければ 0、あればすべての誤りを出力して 1 を返す）
+func validateConfig(cmd *commandLine, lookupEnv func(key string) (string, bool), stdout io.Writer, stderr io.Writer) int {
+	cfg, err := cmd.loadConfig(lookupEnv)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "設定に誤りはありません（リポジトリ %d 件）\n", len(cfg.Repositories))
+	return 0
+}
+
 func main() {
-	port := flag.Int("port", 18080, "Port for API server")
-	flag.Parse()
-	// リポジトリ URI・付与するタグはコマンドラインパラメータで取得
-	repositoryUri := flag.Arg(0)
-	if repositoryUri == "" {
-		panic("リポジトリの指定がありません")
+	cmd, err := parseCommandLine(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	if cmd.validateOnly {
+		os.Exit(validateConfig(cmd, os.LookupEnv, os.Stdout, os.Stderr))
 	}
-	tagName := flag.Arg(1)
-	if tagName == "" {
-		tagName = "release"
+	// 起動前に設定を確認し、誤りがあればすべてを出力して終了
+	cfg, err := cmd.loadConfig(os.LookupEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	logger, err := api.NewLogger(cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+	zap.ReplaceGlobals(logger)
+	gin.SetMode(gin.ReleaseMode)
+	repositories, err := api.NewRepositoryRegistry(cfg.Repositories, cfg.DefaultRepository)
+	if err != nil {
+		logger.Fatal("invalid repositories", zap.Error(err))
+	}
+	// 認証（API キーは環境変数でも指定可）
+	authenticator, err := api.NewAuthenticator(cfg.Auth, cfg.ApiKeys)
+	if err != nil {
+		logger.Fatal("failed to set up authentication", zap.Error(err))
+	}
+	if authenticator == nil {
+		logger.Warn("authentication is not configured, accepting all requests")
+	}
+	// 権限定義
+	policy, err := api.NewPolicy(cfg.Policies, repositories)
+	if err != nil {
+		logger.Fatal("invalid policies", zap.Error(err))
+	}
+	// リリース履歴の保存先（無効にした場合は記録しない）
+	var releases *api.ReleaseStore
+	if cfg.Features.ReleaseHistory {
+		releases, err = api.OpenReleaseStore(cfg.History.Path)
+		if err != nil {
+			logger.Fatal("failed to open release history", zap.Error(err))
+		}
+	}
+	// 停止時はリリース履歴ファイルを最後に閉じる
+	defer releases.Close()
+	// Webhook の通知
+	notifier, err := api.NewNotifier(cfg.Webhooks)
+	if err != nil {
+		logger.Fatal("invalid webhooks", zap.Error(err))
+	}
+	// ECR クライアント（リージョンごとに使い回す）
+	ecrClients, err := api.NewEcrClientFactory(context.TODO(), cfg.Aws)
+	if err != nil {
+		logger.Fatal("failed to load AWS config", zap.Error(err))
+	}
+	// メトリクス（無効にした場合は記録しない）
+	var metrics *api.Metrics
+	if cfg.Features.Metrics {
+		metrics = api.NewMetrics()
 	}
 	// Server Instance 生成
-	setReleaseTag := api.NewSetReleaseTag(repositoryUri, tagName)
-	s := NewGinSetReleaseTagServer(setReleaseTag, *port)
-	// 停止まで HTTP Request を処理
-	log.Fatal(s.ListenAndServe())
+	setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{
+		Repositories:      repositories,
+		TagNames:          cfg.Tags,
+		ImmutableStrategy: cfg.ImmutableStrategy,
+		Policy:            policy,
+		ApprovalTtl:       cfg.ApprovalTtl,
+		PageSize:          int32(cfg.Images.PageSize),
+		MaxImages:         cfg.Images.MaxImages,
+		RollbackDepth:     cfg.History.RollbackDepth,
+		Releases:          releases,
+		SchedulerEnabled:  cfg.Features.Scheduler && releases != nil,
+		Notifier:          notifier,
+		Metrics:           metrics,
+		EcrClients:        ecrClients,
+		EcrCallTimeout:    cfg.Aws.EcrCallTimeout,
+		OperationTimeout:  cfg.Server.OperationTimeout,
+		ImageCacheTtl:     cfg.Images.CacheTtl,
+		ReadinessTtl:      cfg.Server.ReadinessTtl,
+	})
+	if cfg.Server.WriteTimeout > 0 && (cfg.Server.OperationTimeout <= 0 || cfg.Server.OperationTimeout >= cfg.Server.WriteTimeout) {
+		// タイムアウトした場合の 504 を返す前に接続が切れる
+		logger.Warn("operation timeout should be shorter than write timeout", zap.Duration("operation_timeout", cfg.Server.OperationTimeout), zap.Duration("write_timeout", cfg.Server.WriteTimeout))
+	}
+	// タグが変更不可のリポジトリを確認
+	setReleaseTag.CheckRepositories(api.ContextWithLogger(context.TODO(), logger))
+	// 予約リリースの実行（リリース履歴を記録しない場合は実行しない）
+	var scheduler *api.Scheduler
+	if cfg.Features.Scheduler && releases != nil {
+		scheduler, err = setReleaseTag.StartScheduler(cfg.ScheduleInterval)
+		if err != nil {
+			logger.Fatal("failed to start scheduler", zap.Error(err))
+		}
+	} else if cfg.Features.Scheduler {
+		logger.Warn("scheduler is disabled because release history is disabled")
+	}
+	s := NewGinSetReleaseTagServer(setReleaseTag, authenticator, cfg.Server)
+
+	// SIGTERM・SIGINT を受けるまで HTTP Request を処理
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// 待ち受けに失敗した場合も、実行中の予約リリース・付け替えを待つため同じ手順で停止する
+	serveErr := serve(ctx, s, logger)
+	if serveErr != nil {
+		logger.Error("server failed", zap.Error(serveErr))
+	}
+	stop()
+
+	// 新しいリクエストの受け付けを止め、処理中のリクエストの完了を待つ
+	logger.Info("shutting down", zap.Duration("shutdown_timeout", cfg.Server.ShutdownTimeout))
+	shutdownCtx, cancel := shutdownContext(cfg.Server.ShutdownTimeout)
+	defer cancel()
+	err = s.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Warn("drain period exceeded", zap.Error(err))
+	}
+	// 実行中の予約リリースと付け替え中のリリースタグの完了を待ってから終了
+	scheduler.Stop()
+	setReleaseTag.WaitForReleases()
+	notifier.Close()
+	if serveErr != nil {
+		// os.Exit は defer を実行しないため、先にリリース履歴を閉じる
+		releases.Close()
+		logger.Sync()
+		os.Exit(1)
+	}
+	logger.Info("server stopped")
+}
+
+// HTTP サーバーを起動し、ctx が終わるか待ち受けに失敗するまで待つ（停止は呼び出し元で行う）
+func serve(ctx context.Context, s *http.Server, logger *zap.Logger) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("server started", zap.String("addr", s.Addr))
+		err := s.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-serveErr:
+		return err
+	}
+}
+
+// 停止時に処理中のリクエストの完了を待つ期限（0 の場合は完了するまで待つ）
+func shutdownContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
 }
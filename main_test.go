@@ -2,14 +2,35 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
 	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/smithy-go"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/hmatsu47/set-release-tag-api/api"
 	"github.com/hmatsu47/set-release-tag-api/testdouble"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // go test -v で実行する
@@ -203,7 +224,7 @@ func TestSetReleaseTag2(t *testing.T) {
 		}
 		ctx := context.TODO()
 		// ImageList のテスト
-		imageList, err := api.ImageList(ctx, ecrClient(t), repositoryUri)
+		imageList, err := api.ImageList(ctx, ecrClient(t), repositoryUri, maxResults, 0)
 		assert.NoError(t, err)
 		assert.Equal(t, 1, len(imageList))
 		assert.Equal(t, digest2, imageList[0].Digest)
@@ -213,7 +234,7 @@ func TestSetReleaseTag2(t *testing.T) {
 		assert.Equal(t, 1, len(imageList[0].Tags))
 		assert.Equal(t, tag2, imageList[0].Tags[0])
 		// SetTag のテスト
-		err = api.SetTag(ctx, ecrClient(t), repositoryUri, attachTagName, selectedTagName)
+		_, err = api.SetTag(ctx, ecrClient(t), repositoryUri, []string{attachTagName}, types.ImageIdentifier{ImageTag: aws.String(selectedTagName)})
 		assert.NoError(t, err)
 	})
 }
@@ -309,7 +330,7 @@ func TestSetReleaseTag3(t *testing.T) {
 		}
 		ctx := context.TODO()
 		// ImageList のテスト
-		imageList, err := api.ImageList(ctx, ecrClient(t), repositoryUri)
+		imageList, err := api.ImageList(ctx, ecrClient(t), repositoryUri, maxResults, 0)
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(imageList))
 		assert.Equal(t, digest1, imageList[0].Digest)
@@ -325,7 +346,2972 @@ func TestSetReleaseTag3(t *testing.T) {
 		assert.Equal(t, 1, len(imageList[1].Tags))
 		assert.Equal(t, tag2, imageList[1].Tags[0])
 		// SetTag のテスト
-		err = api.SetTag(ctx, ecrClient(t), repositoryUri, attachTagName, selectedTagName)
+		_, err = api.SetTag(ctx, ecrClient(t), repositoryUri, []string{attachTagName}, types.ImageIdentifier{ImageTag: aws.String(selectedTagName)})
 		assert.NoError(t, err)
 	})
 }
+
+func TestSetReleaseTag4(t *testing.T) {
+	// テスト用のパラメーターを生成
+	repositoryUri := "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"
+	repositoryName := "repository1"
+	registryId := "000000000000"
+	pageSize := int32(2)
+
+	// テスト用の ImageDetails を 3 ページ分（2 件・2 件・1 件）生成
+	baseTime, _ := time.Parse("2006-01-02T15:04:05Z07:00", "2022-09-02T05:00:00Z")
+	var pages [][]types.ImageDetail
+	var page []types.ImageDetail
+	for i := 0; i < 5; i++ {
+		imageDetail :=
+			types.ImageDetail{
+				ImageDigest:      aws.String(fmt.Sprintf("sha256:%064d", i)),
+				ImagePushedAt:    aws.Time(baseTime.Add(time.Duration(i) * time.Minute)),
+				ImageSizeInBytes: aws.Int64(int64(10017365 + i)),
+				ImageTags:        []string{fmt.Sprintf("tag%d", i)},
+				RegistryId:       aws.String(registryId),
+				RepositoryName:   aws.String(repositoryName),
+			}
+		page = append(page, imageDetail)
+		if len(page) == int(pageSize) || i == 4 {
+			pages = append(pages, page)
+			page = nil
+		}
+	}
+
+	// テストケース
+	testParams := testdouble.ECRParams{
+		RepositoryName: repositoryName,
+		RegistryId:     registryId,
+		MaxResults:     pageSize,
+	}
+	mockParams := testdouble.MockECRParams{
+		ECRParams:         testParams,
+		ImageDetailsPages: pages,
+	}
+
+	t.Run("イメージ取得（モック利用／3ページ分を全件取得）", func(t *testing.T) {
+		ecrClient := testdouble.GenerateMockECRAPI(mockParams)
+		imageList, err := api.ImageList(context.TODO(), ecrClient, repositoryUri, pageSize, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, len(imageList))
+		// プッシュ時間の降順
+		assert.Equal(t, "tag4", imageList[0].Tags[0])
+		assert.Equal(t, "tag0", imageList[4].Tags[0])
+	})
+
+	t.Run("イメージ取得（モック利用／取得件数の上限 3 件）", func(t *testing.T) {
+		ecrClient := testdouble.GenerateMockECRAPI(mockParams)
+		imageList, err := api.ImageList(context.TODO(), ecrClient, repositoryUri, pageSize, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(imageList))
+		assert.Equal(t, "tag2", imageList[0].Tags[0])
+	})
+
+	t.Run("イメージ取得（モック利用／ページサイズが範囲外）", func(t *testing.T) {
+		ecrClient := testdouble.GenerateMockECRAPI(mockParams)
+		_, err := api.ImageList(context.TODO(), ecrClient, repositoryUri, 1001, 0)
+		assert.Error(t, err)
+	})
+}
+
+func TestRepositoryRegistry(t *testing.T) {
+	t.Run("設定ファイルからリポジトリ一覧を生成", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		config := `
+tag_name: release
+default_repository: app2
+repositories:
+  - name: app1
+    uri: 000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1
+  - name: app2
+    uri: 000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository2
+`
+		err := os.WriteFile(path, []byte(config), 0600)
+		assert.NoError(t, err)
+		cfg, err := api.LoadConfig(path)
+		assert.NoError(t, err)
+		registry, err := api.NewRepositoryRegistry(cfg.Repositories, cfg.DefaultRepository)
+		assert.NoError(t, err)
+		assert.Equal(t, "app2", registry.Default().Name)
+		repository, ok := registry.Get("app1")
+		assert.True(t, ok)
+		assert.Equal(t, "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1", repository.Uri)
+		_, ok = registry.Get("app3")
+		assert.False(t, ok)
+	})
+
+	t.Run("デフォルト未指定時は先頭のリポジトリ", func(t *testing.T) {
+		repositories := []api.RepositoryConfig{
+			{Name: "app1", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+			{Name: "app2", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository2"},
+		}
+		registry, err := api.NewRepositoryRegistry(repositories, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "app1", registry.Default().Name)
+	})
+
+	t.Run("リポジトリ名の重複・未定義のデフォルトはエラー", func(t *testing.T) {
+		repositories := []api.RepositoryConfig{
+			{Name: "app1", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+			{Name: "app1", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository2"},
+		}
+		_, err := api.NewRepositoryRegistry(repositories, "")
+		assert.Error(t, err)
+		_, err = api.NewRepositoryRegistry(repositories[:1], "app2")
+		assert.Error(t, err)
+		_, err = api.NewRepositoryRegistry(nil, "")
+		assert.Error(t, err)
+	})
+}
+
+func TestSetReleaseTag5(t *testing.T) {
+	// テスト用のパラメーターを生成
+	repositoryUri := "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"
+	repositoryName := "repository1"
+	registryId := "000000000000"
+	attachTagName := "release"
+	selectedTagName := "latest"
+
+	// テスト用の Images（ECR）を生成（digest1 に現在のリリースタグ、digest2 に選択したタグ）
+	digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+	digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+	releasedImage := types.Image{
+		ImageId: &types.ImageIdentifier{
+			ImageDigest: aws.String(digest1),
+			ImageTag:    aws.String(attachTagName),
+		},
+		ImageManifest:  aws.String("{\"test\":\"released\"}"),
+		RegistryId:     aws.String(registryId),
+		RepositoryName: aws.String(repositoryName),
+	}
+	selectedImage := types.Image{
+		ImageId: &types.ImageIdentifier{
+			ImageDigest: aws.String(digest2),
+			ImageTag:    aws.String(selectedTagName),
+		},
+		ImageManifest:  aws.String("{\"test\":\"selected\"}"),
+		RegistryId:     aws.String(registryId),
+		RepositoryName: aws.String(repositoryName),
+	}
+
+	// テストケース
+	testParams := testdouble.ECRParams{
+		RepositoryName:  repositoryName,
+		RegistryId:      registryId,
+		AttachTagName:   attachTagName,
+		SelectedTagName: selectedTagName,
+		Images:          []types.Image{selectedImage},
+		ReleasedImages:  []types.Image{releasedImage},
+	}
+	mockParams := testdouble.MockECRParams{
+		ECRParams: testParams,
+	}
+
+	t.Run("リリースタグ設定（モック利用／付け替え前のダイジェストを取得）", func(t *testing.T) {
+		ecrClient := testdouble.GenerateMockECRAPI(mockParams)
+		result, err := api.SetTag(context.TODO(), ecrClient, repositoryUri, []string{attachTagName}, types.ImageIdentifier{ImageTag: aws.String(selectedTagName)})
+		assert.NoError(t, err)
+		assert.Equal(t, digest1, result.PreviousDigest)
+		assert.Equal(t, digest2, result.Digest)
+	})
+
+	t.Run("リリースタグのロールバック（モック利用／ダイジェスト指定で戻す）", func(t *testing.T) {
+		ecrClient := testdouble.GenerateMockECRAPI(mockParams)
+		result, err := api.SetTag(context.TODO(), ecrClient, repositoryUri, []string{attachTagName}, types.ImageIdentifier{ImageDigest: aws.String(digest1)})
+		assert.NoError(t, err)
+		assert.Equal(t, digest1, result.Digest)
+		// 存在しないダイジェストはエラー
+		_, err = api.SetTag(context.TODO(), ecrClient, repositoryUri, []string{attachTagName}, types.ImageIdentifier{ImageDigest: aws.String("sha256:0000")})
+		assert.Error(t, err)
+	})
+
+	t.Run("リリースタグ設定（モック利用／リリースタグが未設定）", func(t *testing.T) {
+		params := mockParams
+		params.ECRParams.ReleasedImages = nil
+		ecrClient := testdouble.GenerateMockECRAPI(params)
+		result, err := api.SetTag(context.TODO(), ecrClient, repositoryUri, []string{attachTagName}, types.ImageIdentifier{ImageTag: aws.String(selectedTagName)})
+		assert.NoError(t, err)
+		assert.Equal(t, "", result.PreviousDigest)
+		assert.Equal(t, digest2, result.Digest)
+	})
+}
+
+func TestRollbackTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "releases.db")
+	store, err := api.OpenReleaseStore(path)
+	assert.NoError(t, err)
+	defer func() { store.Close() }()
+	add := func(operation api.ReleaseOperation, repository string, previousDigest string, digest string, result api.ReleaseResult) {
+		record := api.Release{
+			ReleasedAt:     time.Now(),
+			Operation:      operation,
+			Repository:     repository,
+			Tag:            "release",
+			Digest:         digest,
+			PreviousDigest: previousDigest,
+			Caller:         "alice",
+			Result:         result,
+		}
+		assert.NoError(t, store.Add(&record))
+	}
+	target := func(depth int) (string, bool) {
+		digest, ok, err := store.RollbackTarget("app1", "release", depth)
+		assert.NoError(t, err)
+		return digest, ok
+	}
+
+	// 履歴がなければ戻せない
+	_, ok := target(5)
+	assert.False(t, ok)
+
+	add(api.ReleaseOperationRelease, "app1", "", "sha256:1", api.Success)
+	add(api.ReleaseOperationRelease, "app1", "sha256:1", "sha256:2", api.Success)
+	add(api.ReleaseOperationRelease, "app2", "sha256:8", "sha256:9", api.Success)
+	add(api.ReleaseOperationRelease, "app1", "sha256:2", "sha256:2", api.Unchanged)
+	add(api.ReleaseOperationRelease, "app1", "sha256:2", "sha256:3", api.Failure)
+	add(api.ReleaseOperationRelease, "app1", "sha256:2", "sha256:3", api.Success)
+
+	t.Run("直前のリリースの前のイメージに戻す", func(t *testing.T) {
+		digest, ok := target(5)
+		assert.True(t, ok)
+		assert.Equal(t, "sha256:2", digest)
+	})
+
+	t.Run("ロールバック済みのリリースは飛ばす", func(t *testing.T) {
+		add(api.ReleaseOperationRollback, "app1", "sha256:3", "sha256:2", api.Success)
+		digest, ok := target(5)
+		assert.True(t, ok)
+		assert.Equal(t, "sha256:1", digest)
+		// 戻せるのは直近 depth 件まで
+		_, ok = target(1)
+		assert.False(t, ok)
+
+		add(api.ReleaseOperationRollback, "app1", "sha256:2", "sha256:1", api.Success)
+		_, ok = target(5)
+		assert.False(t, ok)
+	})
+
+	t.Run("タグ削除はリリースタグが付いていたイメージに戻す", func(t *testing.T) {
+		add(api.ReleaseOperationUntag, "app1", "sha256:1", "", api.Success)
+		digest, ok := target(5)
+		assert.True(t, ok)
+		assert.Equal(t, "sha256:1", digest)
+	})
+
+	t.Run("一部のタグの付与に失敗してもリリースタグを付け替えていれば含める", func(t *testing.T) {
+		tags := []string{"release"}
+		record := api.Release{
+			ReleasedAt:     time.Now(),
+			Operation:      api.ReleaseOperationRelease,
+			Repository:     "app1",
+			Tag:            "release",
+			Tags:           &tags,
+			Digest:         "sha256:4",
+			PreviousDigest: "sha256:5",
+			Caller:         "alice",
+			Result:         api.Failure,
+		}
+		assert.NoError(t, store.Add(&record))
+		digest, ok := target(5)
+		assert.True(t, ok)
+		assert.Equal(t, "sha256:5", digest)
+	})
+
+	t.Run("再オープン後も戻せる", func(t *testing.T) {
+		assert.NoError(t, store.Close())
+		store, err = api.OpenReleaseStore(path)
+		assert.NoError(t, err)
+		digest, ok := target(5)
+		assert.True(t, ok)
+		assert.Equal(t, "sha256:5", digest)
+	})
+}
+
+func TestRollback(t *testing.T) {
+	digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+	digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+	mockParams := imagesMockParams(digest1, digest2)
+	// リリースタグが付いているイメージを PutImage に合わせて変えるモック
+	releasedDigest := digest1
+	digestOf := map[string]string{
+		"{\"test\":\"released\"}": digest1,
+		"{\"test\":\"selected\"}": digest2,
+	}
+	mock := testdouble.GenerateMockECRAPI(mockParams)
+	batchGetImage := mock.BatchGetImageAPI
+	mock.BatchGetImageAPI = func(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error) {
+		if len(params.ImageIds) == 1 && aws.ToString(params.ImageIds[0].ImageTag) == "release" {
+			return batchGetImage(ctx, &ecr.BatchGetImageInput{
+				ImageIds:           []types.ImageIdentifier{{ImageDigest: aws.String(releasedDigest)}},
+				RegistryId:         params.RegistryId,
+				RepositoryName:     params.RepositoryName,
+				AcceptedMediaTypes: params.AcceptedMediaTypes,
+			}, optFns...)
+		}
+		return batchGetImage(ctx, params, optFns...)
+	}
+	putImage := mock.PutImageAPI
+	mock.PutImageAPI = func(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error) {
+		output, err := putImage(ctx, params, optFns...)
+		if err == nil {
+			releasedDigest = digestOf[aws.ToString(params.ImageManifest)]
+		}
+		return output, err
+	}
+	repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+		{Name: "app1", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+	}, "")
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "releases.db")
+	releases, err := api.OpenReleaseStore(path)
+	assert.NoError(t, err)
+	defer func() { releases.Close() }()
+	newHandler := func(rollbackDepth int) http.Handler {
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{
+			Repositories:  repositories,
+			TagNames:      []string{"release"},
+			RollbackDepth: rollbackDepth,
+			Releases:      releases,
+			EcrClients:    testdouble.MockECRClientProvider{API: mock},
+		})
+		return NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{}).Handler
+	}
+	send := func(handler http.Handler, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("リリース前の状態へ順に戻す", func(t *testing.T) {
+		handler := newHandler(0)
+		// 履歴がなければ戻せない
+		w := send(handler, "/images/rollback", "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = send(handler, "/images", `{"tag":"latest"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, digest2, releasedDigest)
+		w = send(handler, "/images", fmt.Sprintf(`{"digest":"%s"}`, digest1))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, digest1, releasedDigest)
+
+		w = send(handler, "/images/rollback", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, digest2, releasedDigest)
+		w = send(handler, "/repositories/app1/images/rollback", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, digest1, releasedDigest)
+		// 最初のリリースより前には戻せない
+		w = send(handler, "/images/rollback", "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, digest1, releasedDigest)
+
+		list, _, err := releases.List("app1", 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ReleaseOperationRollback, list[0].Operation)
+		assert.Equal(t, digest1, list[0].Digest)
+		assert.Equal(t, digest2, list[0].PreviousDigest)
+	})
+
+	t.Run("再起動後も履歴から戻せる", func(t *testing.T) {
+		w := send(newHandler(0), "/images", `{"tag":"latest"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, digest2, releasedDigest)
+
+		assert.NoError(t, releases.Close())
+		releases, err = api.OpenReleaseStore(path)
+		assert.NoError(t, err)
+		w = send(newHandler(0), "/images/rollback", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, digest1, releasedDigest)
+	})
+
+	t.Run("戻せるのは直近の保持数まで", func(t *testing.T) {
+		handler := newHandler(1)
+		w := send(handler, "/images", `{"tag":"latest"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = send(handler, "/images", fmt.Sprintf(`{"digest":"%s"}`, digest1))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = send(handler, "/images/rollback", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, digest2, releasedDigest)
+		w = send(handler, "/images/rollback", "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, digest2, releasedDigest)
+	})
+
+	t.Run("リリース履歴を記録しない場合は使えない", func(t *testing.T) {
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{
+			Repositories: repositories,
+			TagNames:     []string{"release"},
+			EcrClients:   testdouble.MockECRClientProvider{API: mock},
+		})
+		handler := NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{}).Handler
+		w := send(handler, "/images/rollback", "")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+
+	t.Run("停止処理の開始後は付け替え・削除を待たずに失敗", func(t *testing.T) {
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{
+			Repositories: repositories,
+			TagNames:     []string{"release"},
+			Releases:     releases,
+			EcrClients:   testdouble.MockECRClientProvider{API: mock},
+		})
+		handler := NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{}).Handler
+		setReleaseTag.WaitForReleases()
+		// 2 回目の呼び出しもブロックしない
+		setReleaseTag.WaitForReleases()
+		current := releasedDigest
+
+		w := send(handler, "/images", `{"tag":"latest"}`)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), api.ErrReleasesClosed.Error())
+		w = send(handler, "/images/rollback", "")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		req := httptest.NewRequest(http.MethodDelete, "/images/tags/latest", nil)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, current, releasedDigest)
+	})
+}
+
+func TestReleaseStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "releases.db")
+	store, err := api.OpenReleaseStore(path)
+	assert.NoError(t, err)
+
+	// app1 に 3 件、app2 に 2 件を交互に記録
+	baseTime, _ := time.Parse("2006-01-02T15:04:05Z07:00", "2022-09-02T05:00:00Z")
+	for i, name := range []string{"app1", "app2", "app1", "app2", "app1"} {
+		record := api.Release{
+			ReleasedAt: baseTime.Add(time.Duration(i) * time.Minute),
+			Operation:  api.ReleaseOperationRelease,
+			Repository: name,
+			Tag:        "release",
+			SourceTag:  fmt.Sprintf("tag%d", i),
+			Digest:     fmt.Sprintf("sha256:%064d", i),
+			Caller:     "127.0.0.1",
+			Result:     api.Success,
+		}
+		err = store.Add(&record)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(i+1), record.Id)
+	}
+
+	t.Run("全リポジトリの履歴を新しい順にページ単位で取得", func(t *testing.T) {
+		releases, nextCursor, err := store.List("", 0, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(releases))
+		assert.Equal(t, int64(5), releases[0].Id)
+		assert.Equal(t, int64(4), releases[1].Id)
+		assert.Equal(t, int64(4), nextCursor)
+
+		releases, nextCursor, err = store.List("", nextCursor, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3, 2}, []int64{releases[0].Id, releases[1].Id})
+
+		releases, nextCursor, err = store.List("", nextCursor, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(releases))
+		assert.Equal(t, "tag0", releases[0].SourceTag)
+		assert.Equal(t, int64(0), nextCursor)
+	})
+
+	t.Run("リポジトリ指定で取得", func(t *testing.T) {
+		releases, nextCursor, err := store.List("app2", 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(releases))
+		assert.Equal(t, int64(4), releases[0].Id)
+		assert.Equal(t, int64(2), releases[1].Id)
+		assert.Equal(t, int64(0), nextCursor)
+	})
+
+	t.Run("再オープン後も履歴を保持", func(t *testing.T) {
+		err := store.Close()
+		assert.NoError(t, err)
+		store, err = api.OpenReleaseStore(path)
+		assert.NoError(t, err)
+		defer store.Close()
+		releases, _, err := store.List("", 100, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, len(releases))
+	})
+}
+
+func TestSetReleaseTag6(t *testing.T) {
+	// テスト用のパラメーターを生成
+	repositoryUri := "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"
+	repositoryName := "repository1"
+	registryId := "000000000000"
+
+	// テスト用の ImageDetails を生成（digest1 はタグ 2 つ、digest2 はタグ 1 つ）
+	digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+	digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+	imageDetails := []types.ImageDetail{
+		{
+			ImageDigest:    aws.String(digest1),
+			ImageTags:      []string{"latest", "release"},
+			RegistryId:     aws.String(registryId),
+			RepositoryName: aws.String(repositoryName),
+		},
+		{
+			ImageDigest:    aws.String(digest2),
+			ImageTags:      []string{"old"},
+			RegistryId:     aws.String(registryId),
+			RepositoryName: aws.String(repositoryName),
+		},
+	}
+
+	// テストケース
+	testParams := testdouble.ECRParams{
+		RepositoryName: repositoryName,
+		RegistryId:     registryId,
+		ImageDetails:   imageDetails,
+	}
+	mockParams := testdouble.MockECRParams{
+		ECRParams: testParams,
+	}
+	ecrClient := testdouble.GenerateMockECRAPI(mockParams)
+
+	t.Run("タグの削除（モック利用／他のタグが残るイメージ）", func(t *testing.T) {
+		result, err := api.RemoveTag(context.TODO(), ecrClient, repositoryUri, "release", false)
+		assert.NoError(t, err)
+		assert.Equal(t, digest1, result.PreviousDigest)
+	})
+
+	t.Run("タグの削除（モック利用／イメージの最後のタグ）", func(t *testing.T) {
+		_, err := api.RemoveTag(context.TODO(), ecrClient, repositoryUri, "old", false)
+		assert.ErrorIs(t, err, api.ErrLastImageTag)
+		// force 指定時は外す
+		result, err := api.RemoveTag(context.TODO(), ecrClient, repositoryUri, "old", true)
+		assert.NoError(t, err)
+		assert.Equal(t, digest2, result.PreviousDigest)
+	})
+
+	t.Run("タグの削除（モック利用／存在しないタグ）", func(t *testing.T) {
+		_, err := api.RemoveTag(context.TODO(), ecrClient, repositoryUri, "none", true)
+		assert.ErrorIs(t, err, api.ErrImageTagNotFound)
+	})
+}
+
+func TestPostImagesValidation(t *testing.T) {
+	repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+		{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+	}, "")
+	assert.NoError(t, err)
+	setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}})
+	handler := NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{}).Handler
+
+	digest := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+	cases := []struct {
+		name string
+		body string
+	}{
+		{"tag も digest も未指定", `{}`},
+		{"tag と digest を同時に指定", fmt.Sprintf(`{"tag":"latest","digest":"%s"}`, digest)},
+		{"digest の形式誤り（sha256 以外）", `{"digest":"md5:4d2653f861f1c4cb187f1a61f97b9af7"}`},
+		{"digest の形式誤り（桁数不足）", `{"digest":"sha256:4d2653f861f1c4cb"}`},
+		{"digest の形式誤り（大文字）", `{"digest":"sha256:4D2653F861F1C4CB187F1A61F97B9AF7ADEC9EC1986D8E253052CFA60FD7372F"}`},
+	}
+	for _, v := range cases {
+		t.Run(fmt.Sprintf("リクエストの検証（%s）", v.name), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/images", strings.NewReader(v.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestSetReleaseTag7(t *testing.T) {
+	// テスト用のパラメーターを生成
+	repositoryUri := "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"
+	repositoryName := "repository1"
+	registryId := "000000000000"
+	attachTagName := "release"
+	selectedTagName := "latest"
+	digest := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+
+	// マルチアーキテクチャ（linux/amd64・linux/arm64）のマニフェストを持つイメージ
+	cases := []struct {
+		name      string
+		mediaType string
+		manifest  string
+	}{
+		{
+			"OCI イメージインデックス",
+			"application/vnd.oci.image.index.v1+json",
+			`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[{"platform":{"architecture":"amd64","os":"linux"}},{"platform":{"architecture":"arm64","os":"linux"}}]}`,
+		},
+		{
+			"Docker マニフェストリスト",
+			"application/vnd.docker.distribution.manifest.list.v2+json",
+			`{"schemaVersion":2,"mediaType":"application/vnd.docker.distribution.manifest.list.v2+json","manifests":[{"platform":{"architecture":"amd64","os":"linux"}},{"platform":{"architecture":"arm64","os":"linux"}}]}`,
+		},
+	}
+	for _, v := range cases {
+		image := types.Image{
+			ImageId: &types.ImageIdentifier{
+				ImageDigest: aws.String(digest),
+				ImageTag:    aws.String(selectedTagName),
+			},
+			ImageManifest:          aws.String(v.manifest),
+			ImageManifestMediaType: aws.String(v.mediaType),
+			RegistryId:             aws.String(registryId),
+			RepositoryName:         aws.String(repositoryName),
+		}
+		testParams := testdouble.ECRParams{
+			RepositoryName:  repositoryName,
+			RegistryId:      registryId,
+			AttachTagName:   attachTagName,
+			SelectedTagName: selectedTagName,
+			Images:          []types.Image{image},
+		}
+		mockParams := testdouble.MockECRParams{
+			ECRParams: testParams,
+		}
+
+		t.Run(fmt.Sprintf("リリースタグ設定（モック利用／%s のメディアタイプを引き継ぐ）", v.name), func(t *testing.T) {
+			ecrClient := testdouble.GenerateMockECRAPI(mockParams)
+			result, err := api.SetTag(context.TODO(), ecrClient, repositoryUri, []string{attachTagName}, types.ImageIdentifier{ImageTag: aws.String(selectedTagName)})
+			assert.NoError(t, err)
+			assert.Equal(t, digest, result.Digest)
+		})
+	}
+}
+
+func TestSetReleaseTag8(t *testing.T) {
+	// テスト用のパラメーターを生成
+	repositoryUri := "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"
+	repositoryName := "repository1"
+	registryId := "000000000000"
+	attachTagName := "release"
+	selectedTagName := "latest"
+
+	// テスト用の Images（ECR）を生成（digest1 に現在のリリースタグ、digest2 に選択したタグ）
+	digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+	digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+	releasedImage := types.Image{
+		ImageId: &types.ImageIdentifier{
+			ImageDigest: aws.String(digest1),
+			ImageTag:    aws.String(attachTagName),
+		},
+		ImageManifest:  aws.String("{\"test\":\"released\"}"),
+		RegistryId:     aws.String(registryId),
+		RepositoryName: aws.String(repositoryName),
+	}
+	selectedImage := types.Image{
+		ImageId: &types.ImageIdentifier{
+			ImageDigest: aws.String(digest2),
+			ImageTag:    aws.String(selectedTagName),
+		},
+		ImageManifest:  aws.String("{\"test\":\"selected\"}"),
+		RegistryId:     aws.String(registryId),
+		RepositoryName: aws.String(repositoryName),
+	}
+
+	// テストケース
+	testParams := testdouble.ECRParams{
+		RepositoryName:  repositoryName,
+		RegistryId:      registryId,
+		AttachTagName:   attachTagName,
+		SelectedTagName: selectedTagName,
+		Images:          []types.Image{selectedImage},
+		ReleasedImages:  []types.Image{releasedImage},
+	}
+	mockParams := testdouble.MockECRParams{
+		ECRParams: testParams,
+	}
+
+	t.Run("リリースタグ設定の dry run（モック利用／PutImage を呼ばない）", func(t *testing.T) {
+		ecrClient := testdouble.GenerateMockECRAPI(mockParams)
+		putImageCalled := false
+		ecrClient.PutImageAPI = func(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error) {
+			putImageCalled = true
+			return nil, errors.New("dry run で PutImage が呼ばれました")
+		}
+		result, err := api.PlanTag(context.TODO(), ecrClient, repositoryUri, []string{attachTagName}, types.ImageIdentifier{ImageTag: aws.String(selectedTagName)})
+		assert.NoError(t, err)
+		assert.False(t, putImageCalled)
+		assert.Equal(t, digest1, result.PreviousDigest)
+		assert.Equal(t, digest2, result.Digest)
+	})
+}
+
+func TestSetReleaseTag9(t *testing.T) {
+	// テスト用のパラメーターを生成
+	repositoryUri := "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"
+	repositoryName := "repository1"
+	registryId := "000000000000"
+	attachTagName := "release"
+	selectedTagName := "latest"
+
+	// テスト用の Images（ECR）を生成
+	digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+	digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+	selectedImage := types.Image{
+		ImageId: &types.ImageIdentifier{
+			ImageDigest: aws.String(digest2),
+			ImageTag:    aws.String(selectedTagName),
+		},
+		ImageManifest:  aws.String("{\"test\":\"selected\"}"),
+		RegistryId:     aws.String(registryId),
+		RepositoryName: aws.String(repositoryName),
+	}
+	releasedImage := selectedImage
+	releasedImage.ImageId = &types.ImageIdentifier{
+		ImageDigest: aws.String(digest2),
+		ImageTag:    aws.String(attachTagName),
+	}
+
+	t.Run("リリースタグ設定（モック利用／既に対象イメージにリリースタグが付いている）", func(t *testing.T) {
+		mockParams := testdouble.MockECRParams{
+			ECRParams: testdouble.ECRParams{
+				RepositoryName:  repositoryName,
+				RegistryId:      registryId,
+				AttachTagName:   attachTagName,
+				SelectedTagName: selectedTagName,
+				Images:          []types.Image{selectedImage},
+				ReleasedImages:  []types.Image{releasedImage},
+			},
+		}
+		ecrClient := testdouble.GenerateMockECRAPI(mockParams)
+		putImageCalled := false
+		ecrClient.PutImageAPI = func(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error) {
+			putImageCalled = true
+			return nil, errors.New("変更不要なのに PutImage が呼ばれました")
+		}
+		result, err := api.SetTag(context.TODO(), ecrClient, repositoryUri, []string{attachTagName}, types.ImageIdentifier{ImageTag: aws.String(selectedTagName)})
+		assert.NoError(t, err)
+		assert.False(t, putImageCalled)
+		assert.True(t, result.Unchanged)
+		assert.Equal(t, digest2, result.PreviousDigest)
+		assert.Equal(t, digest2, result.Digest)
+	})
+
+	t.Run("リリースタグ設定（モック利用／PutImage が ImageAlreadyExistsException）", func(t *testing.T) {
+		otherImage := releasedImage
+		otherImage.ImageId = &types.ImageIdentifier{
+			ImageDigest: aws.String(digest1),
+			ImageTag:    aws.String(attachTagName),
+		}
+		mockParams := testdouble.MockECRParams{
+			ECRParams: testdouble.ECRParams{
+				RepositoryName:  repositoryName,
+				RegistryId:      registryId,
+				AttachTagName:   attachTagName,
+				SelectedTagName: selectedTagName,
+				Images:          []types.Image{selectedImage},
+				ReleasedImages:  []types.Image{otherImage},
+			},
+		}
+		ecrClient := testdouble.GenerateMockECRAPI(mockParams)
+		ecrClient.PutImageAPI = func(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error) {
+			return nil, &types.ImageAlreadyExistsException{Message: aws.String("Image with digest and tag already exists")}
+		}
+		result, err := api.SetTag(context.TODO(), ecrClient, repositoryUri, []string{attachTagName}, types.ImageIdentifier{ImageTag: aws.String(selectedTagName)})
+		assert.NoError(t, err)
+		assert.True(t, result.Unchanged)
+		assert.Equal(t, digest2, result.Digest)
+	})
+}
+
+func TestTagTemplates(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 30, 15, 0, time.UTC)
+	digest := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+
+	t.Run("タグテンプレートの検証", func(t *testing.T) {
+		assert.NoError(t, api.ValidateTagTemplates([]string{"release", "release-{date}-{seq}", "prod-{source}", "{date}{time}"}))
+		assert.Error(t, api.ValidateTagTemplates([]string{}))
+		assert.Error(t, api.ValidateTagTemplates([]string{"release-{sha}"}))
+		assert.Error(t, api.ValidateTagTemplates([]string{"release-{seq}-{seq}"}))
+		assert.Error(t, api.ValidateTagTemplates([]string{"release/{date}"}))
+	})
+
+	t.Run("タグ名の生成", func(t *testing.T) {
+		existingTags := []string{"release", "release-20261018-1", "release-20261018-2", "release-20261017-5", "release-20261018-x"}
+		tagNames := api.RenderTagNames([]string{"release", "release-{date}-{seq}", "prod-{source}", "build-{date}{time}"}, now, "latest", existingTags)
+		assert.Equal(t, []string{"release", "release-20261018-3", "prod-latest", "build-20261018093015"}, tagNames)
+	})
+
+	t.Run("タグ名の生成（既存のタグがない／ダイジェスト指定）", func(t *testing.T) {
+		tagNames := api.RenderTagNames([]string{"release-{date}-{seq}", "prod-{source}"}, now, digest, nil)
+		assert.Equal(t, []string{"release-20261018-1", "prod-4d2653f861f1"}, tagNames)
+	})
+
+	t.Run("プレースホルダーを含まないタグ", func(t *testing.T) {
+		assert.Equal(t, []string{"release", "stable"}, api.FixedTagNames([]string{"release", "release-{date}-{seq}", "stable"}))
+	})
+
+	t.Run("同時にリリースしても連番のタグは重複しない（モック利用）", func(t *testing.T) {
+		digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+		digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+		// タグの付け替えを反映するモック（タグ → ダイジェスト）
+		var tagsLock sync.Mutex
+		tags := map[string]string{"latest": digest2}
+		manifests := map[string]string{digest1: "{\"test\":\"released\"}", digest2: "{\"test\":\"selected\"}"}
+		imageOf := func(digest string) types.Image {
+			return types.Image{
+				ImageId:        &types.ImageIdentifier{ImageDigest: aws.String(digest)},
+				ImageManifest:  aws.String(manifests[digest]),
+				RegistryId:     aws.String("000000000000"),
+				RepositoryName: aws.String("repository1"),
+			}
+		}
+		mock := testdouble.GenerateMockECRAPI(releaseMockParams(digest1, digest2))
+		mock.DescribeImagesAPI = func(ctx context.Context, params *ecr.DescribeImagesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
+			tagsLock.Lock()
+			defer func() {
+				tagsLock.Unlock()
+				// 既存のタグの取得後に別のリリースが割り込めるよう待つ
+				time.Sleep(50 * time.Millisecond)
+			}()
+			var details []types.ImageDetail
+			for digest := range manifests {
+				detail := types.ImageDetail{ImageDigest: aws.String(digest)}
+				for tag, v := range tags {
+					if v == digest {
+						detail.ImageTags = append(detail.ImageTags, tag)
+					}
+				}
+				details = append(details, detail)
+			}
+			return &ecr.DescribeImagesOutput{ImageDetails: details}, nil
+		}
+		mock.BatchGetImageAPI = func(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error) {
+			tagsLock.Lock()
+			defer tagsLock.Unlock()
+			imageId := params.ImageIds[0]
+			digest := aws.ToString(imageId.ImageDigest)
+			if imageId.ImageTag != nil {
+				digest = tags[aws.ToString(imageId.ImageTag)]
+			}
+			if _, ok := manifests[digest]; !ok {
+				return &ecr.BatchGetImageOutput{Failures: []types.ImageFailure{{FailureCode: types.ImageFailureCodeImageNotFound, ImageId: &imageId}}}, nil
+			}
+			return &ecr.BatchGetImageOutput{Images: []types.Image{imageOf(digest)}}, nil
+		}
+		mock.PutImageAPI = func(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error) {
+			tagsLock.Lock()
+			defer tagsLock.Unlock()
+			for digest, manifest := range manifests {
+				if manifest == aws.ToString(params.ImageManifest) {
+					tags[aws.ToString(params.ImageTag)] = digest
+					image := imageOf(digest)
+					return &ecr.PutImageOutput{Image: &image}, nil
+				}
+			}
+			return nil, errors.New("PutImageを呼び出すときのImageManifestの指定が間違っています")
+		}
+		repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+			{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+		}, "")
+		assert.NoError(t, err)
+		newHandler := func(tagNames []string) http.Handler {
+			setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{
+				Repositories: repositories,
+				TagNames:     tagNames,
+				EcrClients:   testdouble.MockECRClientProvider{API: mock},
+			})
+			return NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{}).Handler
+		}
+		send := func(handler http.Handler, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/images", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+
+		handler := newHandler([]string{"release", "release-{date}-{seq}"})
+		var wg sync.WaitGroup
+		codes := make([]int, 2)
+		for i, body := range []string{`{"tag":"latest"}`, fmt.Sprintf(`{"digest":"%s"}`, digest1)} {
+			wg.Add(1)
+			go func(i int, body string) {
+				defer wg.Done()
+				codes[i] = send(handler, body).Code
+			}(i, body)
+		}
+		wg.Wait()
+		assert.Equal(t, []int{http.StatusOK, http.StatusOK}, codes)
+		// 2 つのリリースがそれぞれ別の番号のタグを残す
+		markers := make(map[string]string)
+		for tag, digest := range tags {
+			if strings.HasPrefix(tag, "release-") {
+				markers[digest] = tag
+			}
+		}
+		assert.Len(t, markers, 2)
+		assert.True(t, strings.HasSuffix(markers[digest1], "-1") != strings.HasSuffix(markers[digest2], "-1"))
+
+		// テンプレートから生成したタグが別のイメージに付いている場合は付け替えない
+		release := tags["release"]
+		tags["prod-latest"] = release
+		tags["latest"] = digest1
+		if release == digest1 {
+			tags["latest"] = digest2
+		}
+		w := send(newHandler([]string{"release", "prod-{source}"}), `{"tag":"latest"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), api.ErrTagInUse.Error())
+		assert.Equal(t, release, tags["release"])
+		assert.Equal(t, release, tags["prod-latest"])
+	})
+}
+
+func TestSetReleaseTag10(t *testing.T) {
+	// テスト用のパラメーターを生成
+	repositoryUri := "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"
+	repositoryName := "repository1"
+	registryId := "000000000000"
+	attachTagName := "release"
+	selectedTagName := "latest"
+	digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+	digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+	selectedImage := types.Image{
+		ImageId: &types.ImageIdentifier{
+			ImageDigest: aws.String(digest2),
+			ImageTag:    aws.String(selectedTagName),
+		},
+		ImageManifest:  aws.String("{\"test\":\"selected\"}"),
+		RegistryId:     aws.String(registryId),
+		RepositoryName: aws.String(repositoryName),
+	}
+	releasedImage := types.Image{
+		ImageId: &types.ImageIdentifier{
+			ImageDigest: aws.String(digest1),
+			ImageTag:    aws.String(attachTagName),
+		},
+		ImageManifest:  aws.String("{\"test\":\"released\"}"),
+		RegistryId:     aws.String(registryId),
+		RepositoryName: aws.String(repositoryName),
+	}
+	tagNames := []string{attachTagName, "release-20261018-1", "prod-latest"}
+
+	t.Run("複数タグ設定（モック利用）", func(t *testing.T) {
+		mockParams := testdouble.MockECRParams{
+			ECRParams: testdouble.ECRParams{
+				RepositoryName:     repositoryName,
+				RegistryId:         registryId,
+				AttachTagName:      attachTagName,
+				SelectedTagName:    selectedTagName,
+				Images:             []types.Image{selectedImage},
+				ReleasedImages:     []types.Image{releasedImage},
+				AdditionalTagNames: tagNames[1:],
+			},
+		}
+		result, err := api.SetTag(context.TODO(), testdouble.GenerateMockECRAPI(mockParams), repositoryUri, tagNames, types.ImageIdentifier{ImageTag: aws.String(selectedTagName)})
+		assert.NoError(t, err)
+		assert.False(t, result.Unchanged)
+		assert.Equal(t, digest1, result.PreviousDigest)
+		assert.Equal(t, tagNames, result.AppliedTags())
+		assert.Empty(t, result.FailedTags())
+	})
+
+	t.Run("複数タグ設定（モック利用／一部のタグの付与に失敗）", func(t *testing.T) {
+		mockParams := testdouble.MockECRParams{
+			ECRParams: testdouble.ECRParams{
+				RepositoryName:     repositoryName,
+				RegistryId:         registryId,
+				AttachTagName:      attachTagName,
+				SelectedTagName:    selectedTagName,
+				Images:             []types.Image{selectedImage},
+				ReleasedImages:     []types.Image{releasedImage},
+				AdditionalTagNames: tagNames[1:],
+				FailTagNames:       []string{"release-20261018-1"},
+			},
+		}
+		result, err := api.SetTag(context.TODO(), testdouble.GenerateMockECRAPI(mockParams), repositoryUri, tagNames, types.ImageIdentifier{ImageTag: aws.String(selectedTagName)})
+		var tagApplyErr *api.TagApplyError
+		assert.True(t, errors.As(err, &tagApplyErr))
+		assert.Equal(t, []string{attachTagName, "prod-latest"}, result.AppliedTags())
+		assert.Equal(t, []string{"release-20261018-1"}, result.FailedTags())
+		assert.Contains(t, err.Error(), "release-20261018-1")
+	})
+
+	t.Run("複数タグ設定（モック利用／リリースタグが既に対象イメージに付いている）", func(t *testing.T) {
+		alreadyReleased := selectedImage
+		mockParams := testdouble.MockECRParams{
+			ECRParams: testdouble.ECRParams{
+				RepositoryName:     repositoryName,
+				RegistryId:         registryId,
+				AttachTagName:      attachTagName,
+				SelectedTagName:    selectedTagName,
+				Images:             []types.Image{selectedImage},
+				ReleasedImages:     []types.Image{alreadyReleased},
+				AdditionalTagNames: tagNames[1:],
+				FailTagNames:       tagNames[1:],
+			},
+		}
+		result, err := api.SetTag(context.TODO(), testdouble.GenerateMockECRAPI(mockParams), repositoryUri, tagNames, types.ImageIdentifier{ImageTag: aws.String(selectedTagName)})
+		assert.NoError(t, err)
+		assert.True(t, result.Unchanged)
+	})
+}
+
+func TestImmutableRepository(t *testing.T) {
+	repositoryUri := "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"
+	repositoryName := "repository1"
+	registryId := "000000000000"
+
+	t.Run("タグの変更可否の取得（モック利用）", func(t *testing.T) {
+		for _, mutability := range []types.ImageTagMutability{"", types.ImageTagMutabilityMutable, types.ImageTagMutabilityImmutable} {
+			mockParams := testdouble.MockECRParams{
+				ECRParams: testdouble.ECRParams{
+					RepositoryName:     repositoryName,
+					RegistryId:         registryId,
+					ImageTagMutability: mutability,
+				},
+			}
+			result, err := api.TagMutability(context.TODO(), testdouble.GenerateMockECRAPI(mockParams), repositoryUri)
+			assert.NoError(t, err)
+			if mutability == types.ImageTagMutabilityImmutable {
+				assert.Equal(t, types.ImageTagMutabilityImmutable, result)
+			} else {
+				assert.Equal(t, types.ImageTagMutabilityMutable, result)
+			}
+		}
+	})
+
+	t.Run("タグの変更可否の取得（モック利用／リポジトリが存在しない）", func(t *testing.T) {
+		mockParams := testdouble.MockECRParams{
+			ECRParams: testdouble.ECRParams{
+				RepositoryName: "repository2",
+				RegistryId:     registryId,
+			},
+		}
+		_, err := api.TagMutability(context.TODO(), testdouble.GenerateMockECRAPI(mockParams), repositoryUri)
+		assert.Error(t, err)
+	})
+
+	t.Run("設定ファイルの immutable_strategy", func(t *testing.T) {
+		for _, v := range []struct {
+			config   string
+			strategy api.RepositoryInfoImmutableStrategy
+			valid    bool
+		}{
+			{config: "tags: [release]\n", strategy: api.Fail, valid: true},
+			{config: "tags: [release, \"release-{date}-{seq}\"]\nimmutable_strategy: unique_tags\n", strategy: api.UniqueTags, valid: true},
+			{config: "tags: [release]\nimmutable_strategy: unique_tags\n", valid: false},
+			{config: "tags: [release]\nimmutable_strategy: skip\n", valid: false},
+		} {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			err := os.WriteFile(path, []byte(v.config+"repositories:\n  - name: app1\n    uri: "+repositoryUri+"\n"), 0600)
+			assert.NoError(t, err)
+			cfg, err := api.LoadConfig(path)
+			if !v.valid {
+				assert.Error(t, err, v.config)
+				continue
+			}
+			assert.NoError(t, err, v.config)
+			assert.Equal(t, v.strategy, cfg.ImmutableStrategy)
+		}
+	})
+}
+
+func TestAuthentication(t *testing.T) {
+	repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+		{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+	}, "")
+	assert.NoError(t, err)
+	setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}})
+	dir := t.TempDir()
+
+	// API キーファイル（old は有効期限切れ）
+	apiKeysPath := filepath.Join(dir, "api-keys.yaml")
+	apiKeys := fmt.Sprintf(`
+keys:
+  - subject: ci
+    groups: [release-managers]
+    hash: %s
+  - subject: old
+    hash: %s
+    expires_at: 2020-01-01T00:00:00Z
+`, api.HashApiKey("ci-key"), api.HashApiKey("old-key"))
+	assert.NoError(t, os.WriteFile(apiKeysPath, []byte(apiKeys), 0600))
+
+	// JWKS ファイル
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	jwksPath := filepath.Join(dir, "jwks.json")
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"key1","use":"sig","n":"%s","e":"%s"}]}`,
+		base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()))
+	assert.NoError(t, os.WriteFile(jwksPath, []byte(jwks), 0600))
+
+	authenticator, err := api.NewAuthenticator(api.AuthConfig{
+		ApiKeysFile: apiKeysPath,
+		Jwt: &api.JwtConfig{
+			JwksFile: jwksPath,
+			Issuer:   "https://idp.example.com",
+			Audience: "set-release-tag",
+		},
+	}, fmt.Sprintf("env:%s", api.HashApiKey("env-key")))
+	assert.NoError(t, err)
+	handler := NewGinSetReleaseTagServer(setReleaseTag, authenticator, ServerConfig{}).Handler
+
+	signedToken := func(method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = "key1"
+		signed, err := token.SignedString(key)
+		assert.NoError(t, err)
+		return signed
+	}
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":    "alice",
+			"iss":    "https://idp.example.com",
+			"aud":    "set-release-tag",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"groups": []string{"developers"},
+		}
+	}
+	expiredClaims := validClaims()
+	expiredClaims["exp"] = time.Now().Add(-time.Hour).Unix()
+	otherAudienceClaims := validClaims()
+	otherAudienceClaims["aud"] = "other"
+	noExpClaims := validClaims()
+	delete(noExpClaims, "exp")
+
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		code   int
+	}{
+		{"認証情報なし", "", "", http.StatusUnauthorized},
+		{"API キー", api.ApiKeyHeader, "ci-key", http.StatusServiceUnavailable},
+		{"API キー（環境変数）", api.ApiKeyHeader, "env-key", http.StatusServiceUnavailable},
+		{"API キー（誤り）", api.ApiKeyHeader, "wrong-key", http.StatusUnauthorized},
+		{"API キー（有効期限切れ）", api.ApiKeyHeader, "old-key", http.StatusUnauthorized},
+		{"JWT", "Authorization", "Bearer " + signedToken(jwt.SigningMethodRS256, privateKey, validClaims()), http.StatusServiceUnavailable},
+		{"JWT（有効期限切れ）", "Authorization", "Bearer " + signedToken(jwt.SigningMethodRS256, privateKey, expiredClaims), http.StatusUnauthorized},
+		{"JWT（aud 誤り）", "Authorization", "Bearer " + signedToken(jwt.SigningMethodRS256, privateKey, otherAudienceClaims), http.StatusUnauthorized},
+		{"JWT（exp なし）", "Authorization", "Bearer " + signedToken(jwt.SigningMethodRS256, privateKey, noExpClaims), http.StatusUnauthorized},
+		{"JWT（HS256）", "Authorization", "Bearer " + signedToken(jwt.SigningMethodHS256, []byte("secret"), validClaims()), http.StatusUnauthorized},
+	}
+	for _, v := range cases {
+		t.Run(fmt.Sprintf("認証（%s）", v.name), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/releases", nil)
+			if v.header != "" {
+				req.Header.Set(v.header, v.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, v.code, w.Code)
+		})
+	}
+
+	t.Run("認証後のリクエストの検証は 400", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/images", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(api.ApiKeyHeader, "ci-key")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("API キーのローテーション", func(t *testing.T) {
+		rotated := fmt.Sprintf("keys:\n  - subject: ci\n    hash: %s\n", api.HashApiKey("ci-key-2"))
+		assert.NoError(t, os.WriteFile(apiKeysPath, []byte(rotated), 0600))
+		// 更新を確実に検知させる
+		modTime := time.Now().Add(time.Minute)
+		assert.NoError(t, os.Chtimes(apiKeysPath, modTime, modTime))
+
+		for key, code := range map[string]int{"ci-key-2": http.StatusServiceUnavailable, "ci-key": http.StatusUnauthorized} {
+			req := httptest.NewRequest(http.MethodGet, "/releases", nil)
+			req.Header.Set(api.ApiKeyHeader, key)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, code, w.Code, key)
+		}
+	})
+}
+
+func TestPolicy(t *testing.T) {
+	repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+		{Name: "app1", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+		{Name: "prod", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository2"},
+	}, "")
+	assert.NoError(t, err)
+	policy, err := api.NewPolicy([]api.PolicyConfig{
+		{Repositories: []string{"*"}, Groups: []string{"developers"}, Permissions: []api.Permission{api.PermissionList}},
+		{Repositories: []string{"*"}, Groups: []string{"release-managers"}, Permissions: []api.Permission{api.PermissionList, api.PermissionRelease, api.PermissionRollback, api.PermissionDeleteTag}},
+		{Repositories: []string{"prod"}, Groups: []string{"prod-release-managers"}, Permissions: []api.Permission{api.PermissionList, api.PermissionRelease, api.PermissionRollback}},
+		{Repositories: []string{"prod"}, Subjects: []string{"auditor"}, Permissions: []api.Permission{api.PermissionList}},
+	}, repositories)
+	assert.NoError(t, err)
+
+	developer := &api.Principal{Subject: "alice", Groups: []string{"developers"}}
+	releaseManager := &api.Principal{Subject: "bob", Groups: []string{"release-managers"}}
+	prodReleaseManager := &api.Principal{Subject: "carol", Groups: []string{"prod-release-managers"}}
+	auditor := &api.Principal{Subject: "auditor"}
+
+	t.Run("権限の判定", func(t *testing.T) {
+		cases := []struct {
+			principal  *api.Principal
+			repository string
+			permission api.Permission
+			allowed    bool
+		}{
+			{developer, "app1", api.PermissionList, true},
+			{developer, "app1", api.PermissionRelease, false},
+			{releaseManager, "app1", api.PermissionRelease, true},
+			{releaseManager, "app1", api.PermissionDeleteTag, true},
+			// prod は明示した定義のみで判定
+			{releaseManager, "prod", api.PermissionRelease, false},
+			{developer, "prod", api.PermissionList, false},
+			{prodReleaseManager, "prod", api.PermissionRelease, true},
+			{prodReleaseManager, "prod", api.PermissionDeleteTag, false},
+			{prodReleaseManager, "app1", api.PermissionList, false},
+			{auditor, "prod", api.PermissionList, true},
+			{nil, "app1", api.PermissionList, false},
+		}
+		for _, v := range cases {
+			err := policy.Authorize(v.principal, v.repository, v.permission)
+			if v.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, api.ErrForbidden)
+				assert.Contains(t, err.Error(), string(v.permission))
+			}
+		}
+	})
+
+	t.Run("権限定義がなければすべて許可", func(t *testing.T) {
+		var none *api.Policy
+		assert.NoError(t, none.Authorize(nil, "prod", api.PermissionRelease))
+	})
+
+	t.Run("権限定義の検証", func(t *testing.T) {
+		_, err := api.NewPolicy([]api.PolicyConfig{{Repositories: []string{"app3"}, Groups: []string{"developers"}, Permissions: []api.Permission{api.PermissionList}}}, repositories)
+		assert.Error(t, err)
+		_, err = api.NewPolicy([]api.PolicyConfig{{Repositories: []string{"*"}, Groups: []string{"developers"}, Permissions: []api.Permission{"deploy"}}}, repositories)
+		assert.Error(t, err)
+		_, err = api.NewPolicy([]api.PolicyConfig{{Repositories: []string{"*"}, Permissions: []api.Permission{api.PermissionList}}}, repositories)
+		assert.Error(t, err)
+	})
+
+	t.Run("ハンドラーでの権限の確認", func(t *testing.T) {
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}, Policy: policy})
+		authenticator, err := api.NewAuthenticator(api.AuthConfig{}, fmt.Sprintf("alice:%s", api.HashApiKey("alice-key")))
+		assert.NoError(t, err)
+		handler := NewGinSetReleaseTagServer(setReleaseTag, authenticator, ServerConfig{}).Handler
+
+		// alice はどのグループにも属さない（環境変数の API キー）
+		req := httptest.NewRequest(http.MethodPost, "/repositories/app1/images", strings.NewReader(`{"tag":"latest"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(api.ApiKeyHeader, "alice-key")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), string(api.PermissionRelease))
+
+		req = httptest.NewRequest(http.MethodDelete, "/images/tags/latest", nil)
+		req.Header.Set(api.ApiKeyHeader, "alice-key")
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), string(api.PermissionDeleteTag))
+	})
+}
+
+func TestReleaseRequests(t *testing.T) {
+	repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+		{Name: "prod", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1", RequireApproval: true},
+	}, "")
+	assert.NoError(t, err)
+	releases, err := api.OpenReleaseStore(filepath.Join(t.TempDir(), "releases.db"))
+	assert.NoError(t, err)
+	defer releases.Close()
+	digest := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+
+	addRequest := func(requestedBy string, expiresAt time.Time) api.ReleaseRequest {
+		request := api.ReleaseRequest{
+			Repository:  "prod",
+			Source:      "latest",
+			Digest:      digest,
+			Status:      api.ReleaseRequestStatusPending,
+			RequestedBy: requestedBy,
+			RequestedAt: time.Now(),
+			ExpiresAt:   expiresAt,
+		}
+		assert.NoError(t, releases.AddReleaseRequest(&request))
+		return request
+	}
+
+	t.Run("リリース申請の保存・期限切れ", func(t *testing.T) {
+		pending := addRequest("alice", time.Now().Add(time.Hour))
+		expired := addRequest("alice", time.Now().Add(-time.Minute))
+
+		request, err := releases.GetReleaseRequest(pending.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ReleaseRequestStatusPending, request.Status)
+		request, err = releases.GetReleaseRequest(expired.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ReleaseRequestStatusExpired, request.Status)
+		_, err = releases.GetReleaseRequest(9999)
+		assert.ErrorIs(t, err, api.ErrReleaseRequestNotFound)
+
+		list, err := releases.ListReleaseRequests("prod", api.ReleaseRequestStatusPending)
+		assert.NoError(t, err)
+		assert.Len(t, list, 1)
+		assert.Equal(t, pending.Id, list[0].Id)
+
+		// 更新時は期限切れを保存
+		err = releases.UpdateReleaseRequest(expired.Id, func(r *api.ReleaseRequest) error {
+			return errors.New("更新しない")
+		})
+		assert.Error(t, err)
+		list, err = releases.ListReleaseRequests("", api.ReleaseRequestStatusExpired)
+		assert.NoError(t, err)
+		assert.Len(t, list, 1)
+	})
+
+	t.Run("リリース申請の承認・却下", func(t *testing.T) {
+		authenticator, err := api.NewAuthenticator(api.AuthConfig{}, fmt.Sprintf("alice:%s,bob:%s", api.HashApiKey("alice-key"), api.HashApiKey("bob-key")))
+		assert.NoError(t, err)
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}, Releases: releases})
+		handler := NewGinSetReleaseTagServer(setReleaseTag, authenticator, ServerConfig{}).Handler
+		send := func(method string, path string, body string, key string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			if body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			req.Header.Set(api.ApiKeyHeader, key)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+
+		// 承認が必要なリポジトリには直接リリースできない
+		w := send(http.MethodPost, "/images", `{"tag":"latest"}`, "alice-key")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		// ロールバック・リリースタグの削除も承認なしでは実行できない
+		w = send(http.MethodPost, "/images/rollback", "", "alice-key")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "ロールバックには承認が必要です")
+		w = send(http.MethodPost, "/repositories/prod/images/rollback", "", "alice-key")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = send(http.MethodDelete, "/images/tags/release", "", "alice-key")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "リリースタグ（release）は外せません")
+
+		pending := addRequest("alice", time.Now().Add(time.Hour))
+		// 申請者自身は承認できない
+		w = send(http.MethodPost, fmt.Sprintf("/release-requests/%d/approve", pending.Id), "", "alice-key")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		// 却下後は承認できない
+		w = send(http.MethodPost, fmt.Sprintf("/release-requests/%d/reject", pending.Id), "", "bob-key")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"rejected"`)
+		assert.Contains(t, w.Body.String(), `"decided_by":"bob"`)
+		w = send(http.MethodPost, fmt.Sprintf("/release-requests/%d/approve", pending.Id), "", "bob-key")
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		// 期限切れの申請は承認できない
+		expired := addRequest("alice", time.Now().Add(-time.Minute))
+		w = send(http.MethodPost, fmt.Sprintf("/release-requests/%d/approve", expired.Id), "", "bob-key")
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = send(http.MethodGet, "/release-requests/9999", "", "bob-key")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = send(http.MethodGet, "/release-requests?status=rejected", "", "bob-key")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), fmt.Sprintf(`"id":%d`, pending.Id))
+	})
+
+	t.Run("認証していない場合は申請・承認・却下できない", func(t *testing.T) {
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}, Releases: releases})
+		handler := NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{}).Handler
+		send := func(path string, body string, forwardedFor string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+			if body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			// X-Forwarded-For で別の利用者になりすませないこと
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+
+		w := send("/release-requests", `{"tag":"latest"}`, "192.0.2.1")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		pending := addRequest("192.0.2.1", time.Now().Add(time.Hour))
+		w = send(fmt.Sprintf("/release-requests/%d/approve", pending.Id), "", "192.0.2.2")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = send(fmt.Sprintf("/release-requests/%d/reject", pending.Id), "", "192.0.2.1")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		request, err := releases.GetReleaseRequest(pending.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ReleaseRequestStatusPending, request.Status)
+	})
+}
+
+func TestScheduledReleases(t *testing.T) {
+	repositoryUri := "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"
+	repositoryName := "repository1"
+	registryId := "000000000000"
+	digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+	digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+	repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+		{Name: "default", Uri: repositoryUri},
+	}, "")
+	assert.NoError(t, err)
+	releases, err := api.OpenReleaseStore(filepath.Join(t.TempDir(), "releases.db"))
+	assert.NoError(t, err)
+	defer releases.Close()
+	setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}, Releases: releases, SchedulerEnabled: true})
+
+	addJob := func(scheduledAt time.Time) api.ScheduledRelease {
+		job := api.ScheduledRelease{
+			Repository:  "default",
+			Source:      "latest",
+			Digest:      digest2,
+			ScheduledAt: scheduledAt,
+			Status:      api.ScheduledReleaseStatusPending,
+			CreatedBy:   "alice",
+			CreatedAt:   time.Now(),
+		}
+		assert.NoError(t, releases.AddScheduledRelease(&job))
+		return job
+	}
+
+	t.Run("予約リリースの保存・再起動後の再実行", func(t *testing.T) {
+		now := time.Now()
+		later := addJob(now.Add(time.Hour))
+		due := addJob(now.Add(-time.Minute))
+		running := addJob(now.Add(-time.Hour))
+		assert.NoError(t, releases.UpdateScheduledRelease(running.Id, func(j *api.ScheduledRelease) error {
+			j.Status = api.ScheduledReleaseStatusRunning
+			return nil
+		}))
+
+		// 実行予定日時の順に取得
+		list, err := releases.ListScheduledReleases("default", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{running.Id, due.Id, later.Id}, []int64{list[0].Id, list[1].Id, list[2].Id})
+		jobs, err := releases.DueScheduledReleases(now)
+		assert.NoError(t, err)
+		assert.Len(t, jobs, 1)
+		assert.Equal(t, due.Id, jobs[0].Id)
+
+		// 実行中のまま停止した予約は実行待ちに戻す
+		count, err := releases.ResumeScheduledReleases()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		jobs, err = releases.DueScheduledReleases(now)
+		assert.NoError(t, err)
+		assert.Len(t, jobs, 2)
+
+		for _, v := range []api.ScheduledRelease{later, due, running} {
+			assert.NoError(t, releases.UpdateScheduledRelease(v.Id, func(j *api.ScheduledRelease) error {
+				j.Status = api.ScheduledReleaseStatusCancelled
+				return nil
+			}))
+		}
+		_, err = releases.GetScheduledRelease(9999)
+		assert.ErrorIs(t, err, api.ErrScheduledReleaseNotFound)
+	})
+
+	t.Run("予約リリースの実行（モック利用）", func(t *testing.T) {
+		mockParams := testdouble.MockECRParams{
+			ECRParams: testdouble.ECRParams{
+				RepositoryName:  repositoryName,
+				RegistryId:      registryId,
+				AttachTagName:   "release",
+				SelectedTagName: "latest",
+				Images: []types.Image{
+					{
+						ImageId: &types.ImageIdentifier{
+							ImageDigest: aws.String(digest2),
+							ImageTag:    aws.String("latest"),
+						},
+						ImageManifest:  aws.String("{\"test\":\"selected\"}"),
+						RegistryId:     aws.String(registryId),
+						RepositoryName: aws.String(repositoryName),
+					},
+				},
+				ReleasedImages: []types.Image{
+					{
+						ImageId: &types.ImageIdentifier{
+							ImageDigest: aws.String(digest1),
+							ImageTag:    aws.String("release"),
+						},
+						ImageManifest:  aws.String("{\"test\":\"released\"}"),
+						RegistryId:     aws.String(registryId),
+						RepositoryName: aws.String(repositoryName),
+					},
+				},
+			},
+		}
+		job := addJob(time.Now().Add(-time.Minute))
+		result, err := setReleaseTag.ExecuteScheduledRelease(context.TODO(), testdouble.GenerateMockECRAPI(mockParams), job.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ScheduledReleaseStatusSucceeded, result.Status)
+		assert.NotNil(t, result.ExecutedAt)
+
+		// 実行済みの予約は再実行しない
+		_, err = setReleaseTag.ExecuteScheduledRelease(context.TODO(), testdouble.GenerateMockECRAPI(mockParams), job.Id)
+		assert.ErrorIs(t, err, api.ErrScheduledReleaseNotPending)
+
+		// リリース履歴に予約リリースの ID を記録
+		records, _, err := releases.List("default", 0, 1)
+		assert.NoError(t, err)
+		assert.Len(t, records, 1)
+		assert.Equal(t, job.Id, *records[0].ScheduledReleaseId)
+		assert.Equal(t, "alice", records[0].Caller)
+		assert.Equal(t, digest2, records[0].Digest)
+		assert.Equal(t, digest1, records[0].PreviousDigest)
+	})
+
+	t.Run("予約リリースの作成・取り消し", func(t *testing.T) {
+		handler := NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{}).Handler
+		send := func(method string, path string, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			if body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+
+		// 過去の日時・dry run との同時指定は予約できない
+		w := send(http.MethodPost, "/images", fmt.Sprintf(`{"tag":"latest","scheduled_at":"%s"}`, time.Now().Add(-time.Minute).Format(time.RFC3339)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = send(http.MethodPost, "/images", fmt.Sprintf(`{"tag":"latest","dry_run":true,"scheduled_at":"%s"}`, time.Now().Add(time.Hour).Format(time.RFC3339)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		pending := addJob(time.Now().Add(time.Hour))
+		w = send(http.MethodGet, "/scheduled-releases?status=pending", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), fmt.Sprintf(`"id":%d`, pending.Id))
+
+		w = send(http.MethodDelete, fmt.Sprintf("/scheduled-releases/%d", pending.Id), "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"cancelled"`)
+		// 取り消した予約は実行しない
+		w = send(http.MethodDelete, fmt.Sprintf("/scheduled-releases/%d", pending.Id), "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		_, err := setReleaseTag.ExecuteScheduledRelease(context.TODO(), nil, pending.Id)
+		assert.ErrorIs(t, err, api.ErrScheduledReleaseNotPending)
+
+		w = send(http.MethodGet, "/scheduled-releases/9999", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("予約リリースを実行しない設定では予約を受け付けない（モック利用）", func(t *testing.T) {
+		authenticator, err := api.NewAuthenticator(api.AuthConfig{}, fmt.Sprintf("alice:%s,bob:%s", api.HashApiKey("alice-key"), api.HashApiKey("bob-key")))
+		assert.NoError(t, err)
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{
+			Repositories: repositories,
+			TagNames:     []string{"release"},
+			Releases:     releases,
+			EcrClients:   testdouble.MockECRClientProvider{API: testdouble.GenerateMockECRAPI(releaseMockParams(digest1, digest2))},
+		})
+		handler := NewGinSetReleaseTagServer(setReleaseTag, authenticator, ServerConfig{}).Handler
+		send := func(path string, body string, key string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+			if body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			req.Header.Set(api.ApiKeyHeader, key)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+		scheduledAt := time.Now().Add(time.Hour)
+		before, err := releases.ListScheduledReleases("", "")
+		assert.NoError(t, err)
+
+		w := send("/images", fmt.Sprintf(`{"tag":"latest","scheduled_at":"%s"}`, scheduledAt.Format(time.RFC3339)), "alice-key")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		w = send("/release-requests", fmt.Sprintf(`{"tag":"latest","scheduled_at":"%s"}`, scheduledAt.Format(time.RFC3339)), "alice-key")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		// 予約リリースを実行する設定のときに申請したものも承認しない
+		request := api.ReleaseRequest{
+			Repository:  "default",
+			Source:      "latest",
+			Digest:      digest2,
+			Status:      api.ReleaseRequestStatusPending,
+			RequestedBy: "alice",
+			RequestedAt: time.Now(),
+			ExpiresAt:   time.Now().Add(time.Hour),
+			ScheduledAt: &scheduledAt,
+		}
+		assert.NoError(t, releases.AddReleaseRequest(&request))
+		w = send(fmt.Sprintf("/release-requests/%d/approve", request.Id), "", "bob-key")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "scheduled_at は指定できません")
+		got, err := releases.GetReleaseRequest(request.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ReleaseRequestStatusPending, got.Status)
+
+		// 実行されない予約は作成しない
+		after, err := releases.ListScheduledReleases("", "")
+		assert.NoError(t, err)
+		assert.Equal(t, len(before), len(after))
+	})
+}
+
+func TestWebhooks(t *testing.T) {
+	// 受信した通知（本文と署名）
+	type received struct {
+		body      []byte
+		signature string
+	}
+	newReceiver := func(statusCodes ...int) (*httptest.Server, chan received) {
+		ch := make(chan received, 10)
+		count := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			ch <- received{body: body, signature: r.Header.Get(api.WebhookSignatureHeader)}
+			if count < len(statusCodes) {
+				w.WriteHeader(statusCodes[count])
+			}
+			count++
+		}))
+		return server, ch
+	}
+	receive := func(ch chan received) received {
+		select {
+		case v := <-ch:
+			return v
+		case <-time.After(5 * time.Second):
+			t.Fatal("通知を受信できませんでした")
+		}
+		return received{}
+	}
+	event := api.WebhookEvent{
+		Operation:      api.ReleaseOperationRelease,
+		Result:         api.Success,
+		Repository:     "default",
+		Tag:            "release",
+		Source:         "latest",
+		PreviousDigest: "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f",
+		Digest:         "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62",
+		Actor:          "alice",
+		Timestamp:      time.Now(),
+	}
+
+	t.Run("通知先の設定誤り", func(t *testing.T) {
+		notifier, err := api.NewNotifier(nil)
+		assert.NoError(t, err)
+		assert.Nil(t, notifier)
+		// 通知先がなくても呼び出せる
+		notifier.Notify(event)
+		notifier.Close()
+
+		_, err = api.NewNotifier([]api.WebhookConfig{{Url: "ftp://example.com/hook"}})
+		assert.Error(t, err)
+		_, err = api.NewNotifier([]api.WebhookConfig{{Url: "https://example.com/hook", Format: "xml"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("署名付きの通知・再送", func(t *testing.T) {
+		server, ch := newReceiver(http.StatusInternalServerError, http.StatusOK)
+		defer server.Close()
+		notifier, err := api.NewNotifier([]api.WebhookConfig{
+			{Url: server.URL, Secret: "secret", RetryBackoff: 10 * time.Millisecond},
+		})
+		assert.NoError(t, err)
+		defer notifier.Close()
+		notifier.Notify(event)
+
+		first := receive(ch)
+		second := receive(ch)
+		assert.Equal(t, first.body, second.body)
+		assert.Equal(t, api.SignWebhookPayload("secret", second.body), second.signature)
+		var got api.WebhookEvent
+		assert.NoError(t, json.Unmarshal(second.body, &got))
+		assert.Equal(t, event.Repository, got.Repository)
+		assert.Equal(t, event.PreviousDigest, got.PreviousDigest)
+		assert.Equal(t, event.Digest, got.Digest)
+		assert.Equal(t, "alice", got.Actor)
+	})
+
+	t.Run("Slack 形式の通知", func(t *testing.T) {
+		server, ch := newReceiver()
+		defer server.Close()
+		notifier, err := api.NewNotifier([]api.WebhookConfig{
+			{Url: server.URL, Format: api.WebhookFormatSlack},
+		})
+		assert.NoError(t, err)
+		defer notifier.Close()
+		notifier.Notify(event)
+
+		got := receive(ch)
+		assert.Empty(t, got.signature)
+		var message map[string]string
+		assert.NoError(t, json.Unmarshal(got.body, &message))
+		assert.Contains(t, message["text"], "`default` のタグ `release`")
+		assert.Contains(t, message["text"], "`sha256:4d2653f861f1` → `sha256:20b39162cb05`")
+	})
+
+	t.Run("通知先の応答が遅くても待たない", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		notifier, err := api.NewNotifier([]api.WebhookConfig{{Url: server.URL}})
+		assert.NoError(t, err)
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			notifier.Notify(event)
+		}
+		assert.Less(t, time.Since(start), time.Second)
+		close(release)
+		notifier.Close()
+	})
+
+	t.Run("予約リリースの結果の通知（モック利用）", func(t *testing.T) {
+		repositoryName := "repository1"
+		registryId := "000000000000"
+		repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+			{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+		}, "")
+		assert.NoError(t, err)
+		releases, err := api.OpenReleaseStore(filepath.Join(t.TempDir(), "releases.db"))
+		assert.NoError(t, err)
+		defer releases.Close()
+		server, ch := newReceiver()
+		defer server.Close()
+		notifier, err := api.NewNotifier([]api.WebhookConfig{{Url: server.URL}})
+		assert.NoError(t, err)
+		defer notifier.Close()
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}, Releases: releases, Notifier: notifier})
+
+		mockParams := testdouble.MockECRParams{
+			ECRParams: testdouble.ECRParams{
+				RepositoryName:  repositoryName,
+				RegistryId:      registryId,
+				AttachTagName:   "release",
+				SelectedTagName: "latest",
+				Images: []types.Image{
+					{
+						ImageId: &types.ImageIdentifier{
+							ImageDigest: aws.String(event.Digest),
+							ImageTag:    aws.String("latest"),
+						},
+						ImageManifest:  aws.String("{\"test\":\"selected\"}"),
+						RegistryId:     aws.String(registryId),
+						RepositoryName: aws.String(repositoryName),
+					},
+				},
+				ReleasedImages: []types.Image{
+					{
+						ImageId: &types.ImageIdentifier{
+							ImageDigest: aws.String(event.PreviousDigest),
+							ImageTag:    aws.String("release"),
+						},
+						ImageManifest:  aws.String("{\"test\":\"released\"}"),
+						RegistryId:     aws.String(registryId),
+						RepositoryName: aws.String(repositoryName),
+					},
+				},
+			},
+		}
+		job := api.ScheduledRelease{
+			Repository:  "default",
+			Source:      "latest",
+			Digest:      event.Digest,
+			ScheduledAt: time.Now(),
+			Status:      api.ScheduledReleaseStatusPending,
+			CreatedBy:   "alice",
+			CreatedAt:   time.Now(),
+		}
+		assert.NoError(t, releases.AddScheduledRelease(&job))
+		_, err = setReleaseTag.ExecuteScheduledRelease(context.TODO(), testdouble.GenerateMockECRAPI(mockParams), job.Id)
+		assert.NoError(t, err)
+
+		var got api.WebhookEvent
+		assert.NoError(t, json.Unmarshal(receive(ch).body, &got))
+		assert.Equal(t, api.ReleaseOperationRelease, got.Operation)
+		assert.Equal(t, api.Success, got.Result)
+		assert.Equal(t, "release", got.Tag)
+		assert.Equal(t, event.PreviousDigest, got.PreviousDigest)
+		assert.Equal(t, event.Digest, got.Digest)
+		assert.Equal(t, "alice", got.Actor)
+		assert.NotZero(t, got.ReleaseId)
+	})
+
+	t.Run("タグを付け替える前の失敗も通知・記録（モック利用）", func(t *testing.T) {
+		repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+			{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+		}, "")
+		assert.NoError(t, err)
+		releases, err := api.OpenReleaseStore(filepath.Join(t.TempDir(), "releases.db"))
+		assert.NoError(t, err)
+		defer releases.Close()
+		server, ch := newReceiver()
+		defer server.Close()
+		notifier, err := api.NewNotifier([]api.WebhookConfig{{Url: server.URL}})
+		assert.NoError(t, err)
+		defer notifier.Close()
+
+		immutable := releaseMockParams(event.PreviousDigest, event.Digest)
+		immutable.ECRParams.ImageTagMutability = types.ImageTagMutabilityImmutable
+		throttled := testdouble.GenerateMockECRAPI(releaseMockParams(event.PreviousDigest, event.Digest))
+		throttled.DescribeRepositoriesAPI = func(ctx context.Context, params *ecr.DescribeRepositoriesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
+			return nil, errors.New("ThrottlingException: Rate exceeded")
+		}
+		for _, v := range []struct {
+			name    string
+			mock    testdouble.MockECRAPI
+			code    int
+			message string
+		}{
+			{"タグが変更不可", testdouble.GenerateMockECRAPI(immutable), http.StatusConflict, "IMMUTABLE"},
+			{"DescribeRepositories の失敗", throttled, http.StatusInternalServerError, "ThrottlingException"},
+		} {
+			setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{
+				Repositories: repositories,
+				TagNames:     []string{"release"},
+				Releases:     releases,
+				Notifier:     notifier,
+				EcrClients:   testdouble.MockECRClientProvider{API: v.mock},
+			})
+			handler := NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{}).Handler
+			req := httptest.NewRequest(http.MethodPost, "/images", strings.NewReader(`{"tag":"latest"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, v.code, w.Code, v.name)
+
+			var got api.WebhookEvent
+			assert.NoError(t, json.Unmarshal(receive(ch).body, &got))
+			assert.Equal(t, api.Failure, got.Result, v.name)
+			assert.Equal(t, "release", got.Tag, v.name)
+			assert.Equal(t, "latest", got.Source, v.name)
+			assert.NotZero(t, got.ReleaseId, v.name)
+
+			list, _, err := releases.List("default", 0, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, api.Failure, list[0].Result, v.name)
+			assert.Equal(t, "latest", list[0].SourceTag, v.name)
+			assert.Contains(t, *list[0].Message, v.message, v.name)
+		}
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	repositoryName := "repository1"
+	registryId := "000000000000"
+	digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+	digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+	repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+		{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+	}, "")
+	assert.NoError(t, err)
+	releases, err := api.OpenReleaseStore(filepath.Join(t.TempDir(), "releases.db"))
+	assert.NoError(t, err)
+	defer releases.Close()
+	metrics := api.NewMetrics()
+	setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}, Releases: releases, Metrics: metrics})
+	authenticator, err := api.NewAuthenticator(api.AuthConfig{}, fmt.Sprintf("alice:%s", api.HashApiKey("alice-key")))
+	assert.NoError(t, err)
+	handler := NewGinSetReleaseTagServer(setReleaseTag, authenticator, ServerConfig{}).Handler
+	scrape := func() string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		// メトリクスは認証不要
+		assert.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	t.Run("OpenAPI の操作ごとの HTTP リクエスト", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/releases", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		req := httptest.NewRequest(http.MethodGet, "/repositories/unknown/images", nil)
+		req.Header.Set(api.ApiKeyHeader, "alice-key")
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		body := scrape()
+		assert.Contains(t, body, `set_release_tag_http_requests_total{code="401",operation="GetReleases"} 1`)
+		assert.Contains(t, body, `set_release_tag_http_requests_total{code="404",operation="GetRepositoryImages"} 1`)
+		assert.Contains(t, body, `set_release_tag_http_request_duration_seconds_count{operation="GetReleases"} 1`)
+	})
+
+	t.Run("ECR API の呼び出し・エラー", func(t *testing.T) {
+		ecrClient := api.InstrumentECR(testdouble.MockECRAPI{
+			DescribeImagesAPI: func(ctx context.Context, params *ecr.DescribeImagesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
+				return nil, &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+			},
+		}, metrics)
+		_, err := ecrClient.DescribeImages(context.TODO(), &ecr.DescribeImagesInput{})
+		assert.Error(t, err)
+
+		body := scrape()
+		assert.Contains(t, body, `set_release_tag_ecr_requests_total{method="DescribeImages"} 1`)
+		assert.Contains(t, body, `set_release_tag_ecr_errors_total{code="ThrottlingException",method="DescribeImages"} 1`)
+		assert.Contains(t, body, `set_release_tag_ecr_request_duration_seconds_count{method="DescribeImages"} 1`)
+	})
+
+	t.Run("リポジトリごとのリリース（モック利用）", func(t *testing.T) {
+		mockParams := testdouble.MockECRParams{
+			ECRParams: testdouble.ECRParams{
+				RepositoryName:  repositoryName,
+				RegistryId:      registryId,
+				AttachTagName:   "release",
+				SelectedTagName: "latest",
+				Images: []types.Image{
+					{
+						ImageId: &types.ImageIdentifier{
+							ImageDigest: aws.String(digest2),
+							ImageTag:    aws.String("latest"),
+						},
+						ImageManifest:  aws.String("{\"test\":\"selected\"}"),
+						RegistryId:     aws.String(registryId),
+						RepositoryName: aws.String(repositoryName),
+					},
+				},
+				ReleasedImages: []types.Image{
+					{
+						ImageId: &types.ImageIdentifier{
+							ImageDigest: aws.String(digest1),
+							ImageTag:    aws.String("release"),
+						},
+						ImageManifest:  aws.String("{\"test\":\"released\"}"),
+						RegistryId:     aws.String(registryId),
+						RepositoryName: aws.String(repositoryName),
+					},
+				},
+			},
+		}
+		job := api.ScheduledRelease{
+			Repository:  "default",
+			Source:      "latest",
+			Digest:      digest2,
+			ScheduledAt: time.Now(),
+			Status:      api.ScheduledReleaseStatusPending,
+			CreatedBy:   "alice",
+			CreatedAt:   time.Now(),
+		}
+		assert.NoError(t, releases.AddScheduledRelease(&job))
+		_, err = setReleaseTag.ExecuteScheduledRelease(context.TODO(), api.InstrumentECR(testdouble.GenerateMockECRAPI(mockParams), metrics), job.Id)
+		assert.NoError(t, err)
+
+		body := scrape()
+		assert.Contains(t, body, `set_release_tag_releases_total{operation="release",repository="default",result="success"} 1`)
+		assert.Contains(t, body, `set_release_tag_last_successful_release_timestamp_seconds{repository="default"}`)
+		assert.Contains(t, body, `set_release_tag_ecr_requests_total{method="PutImage"} 1`)
+	})
+}
+
+// リリースタグを releasedDigest のイメージから latest タグの selectedDigest のイメージに付け替えるモック
+func releaseMockParams(releasedDigest string, selectedDigest string) testdouble.MockECRParams {
+	repositoryName := "repository1"
+	registryId := "000000000000"
+	return testdouble.MockECRParams{
+		ECRParams: testdouble.ECRParams{
+			RepositoryName:  repositoryName,
+			RegistryId:      registryId,
+			AttachTagName:   "release",
+			SelectedTagName: "latest",
+			Images: []types.Image{
+				{
+					ImageId: &types.ImageIdentifier{
+						ImageDigest: aws.String(selectedDigest),
+						ImageTag:    aws.String("latest"),
+					},
+					ImageManifest:  aws.String("{\"test\":\"selected\"}"),
+					RegistryId:     aws.String(registryId),
+					RepositoryName: aws.String(repositoryName),
+				},
+			},
+			ReleasedImages: []types.Image{
+				{
+					ImageId: &types.ImageIdentifier{
+						ImageDigest: aws.String(releasedDigest),
+						ImageTag:    aws.String("release"),
+					},
+					ImageManifest:  aws.String("{\"test\":\"released\"}"),
+					RegistryId:     aws.String(registryId),
+					RepositoryName: aws.String(repositoryName),
+				},
+			},
+		},
+	}
+}
+
+// releaseMockParams に一覧取得（ページサイズ 1000）の結果を加えたもの
+func imagesMockParams(releasedDigest string, selectedDigest string) testdouble.MockECRParams {
+	pushedAt, _ := time.Parse("2006-01-02T15:04:05Z07:00", "2022-09-02T05:27:02Z")
+	mockParams := releaseMockParams(releasedDigest, selectedDigest)
+	mockParams.ECRParams.MaxResults = 1000
+	mockParams.ECRParams.ImageDetails = []types.ImageDetail{
+		{
+			ImageDigest:      aws.String(selectedDigest),
+			ImagePushedAt:    aws.Time(pushedAt),
+			ImageSizeInBytes: aws.Int64(10017365),
+			ImageTags:        []string{"latest"},
+			RegistryId:       aws.String("000000000000"),
+			RepositoryName:   aws.String("repository1"),
+		},
+	}
+	return mockParams
+}
+
+func TestLogging(t *testing.T) {
+	digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+	digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+	core, logs := observer.New(zap.DebugLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+	repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+		{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+	}, "")
+	assert.NoError(t, err)
+	// 項目名と値の対応
+	fieldsOf := func(entry observer.LoggedEntry) map[string]interface{} {
+		return entry.ContextMap()
+	}
+
+	t.Run("リクエスト ID の付与・エラーレスポンスへの反映", func(t *testing.T) {
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}})
+		handler := NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{}).Handler
+		send := func(path string, requestId string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if requestId != "" {
+				req.Header.Set(api.RequestIdHeader, requestId)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+
+		// 呼び出し元の指定をそのまま使う
+		w := send("/releases", "req-123")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "req-123", w.Header().Get(api.RequestIdHeader))
+		assert.Contains(t, w.Body.String(), `"request_id":"req-123"`)
+		entries := logs.FilterMessage("request").FilterField(zap.String("request_id", "req-123")).All()
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "/releases", fieldsOf(entries[0])["route"])
+		assert.EqualValues(t, http.StatusServiceUnavailable, fieldsOf(entries[0])["status"])
+
+		// 指定がない場合・ログに混ぜられない文字を含む場合は生成
+		for _, requestId := range []string{"", "bad id\n"} {
+			w = send("/releases", requestId)
+			generated := w.Header().Get(api.RequestIdHeader)
+			assert.NotEmpty(t, generated)
+			assert.NotEqual(t, requestId, generated)
+			assert.Contains(t, w.Body.String(), fmt.Sprintf(`"request_id":"%s"`, generated))
+		}
+
+		// Validation エラーにも含める
+		w = send("/releases?limit=0", "req-456")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"request_id":"req-456"`)
+	})
+
+	t.Run("ECR API の呼び出しのログ", func(t *testing.T) {
+		ctx := api.ContextWithLogger(context.TODO(), zap.L().With(zap.String("request_id", "req-789")))
+		ecrClient := api.InstrumentECR(testdouble.MockECRAPI{
+			DescribeImagesAPI: func(ctx context.Context, params *ecr.DescribeImagesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
+				return nil, &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+			},
+		}, nil)
+		_, err := ecrClient.DescribeImages(ctx, &ecr.DescribeImagesInput{})
+		assert.Error(t, err)
+
+		entries := logs.FilterMessage("ecr call failed").FilterField(zap.String("request_id", "req-789")).All()
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "DescribeImages", fieldsOf(entries[0])["ecr_method"])
+		assert.Equal(t, "ThrottlingException", fieldsOf(entries[0])["error_code"])
+	})
+
+	t.Run("リリースのログ（モック利用）", func(t *testing.T) {
+		releases, err := api.OpenReleaseStore(filepath.Join(t.TempDir(), "releases.db"))
+		assert.NoError(t, err)
+		defer releases.Close()
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}, Releases: releases})
+		job := api.ScheduledRelease{
+			Repository:  "default",
+			Source:      "latest",
+			Digest:      digest2,
+			ScheduledAt: time.Now(),
+			Status:      api.ScheduledReleaseStatusPending,
+			CreatedBy:   "alice",
+			CreatedAt:   time.Now(),
+		}
+		assert.NoError(t, releases.AddScheduledRelease(&job))
+		ctx := api.ContextWithLogger(context.TODO(), zap.L())
+		_, err = setReleaseTag.ExecuteScheduledRelease(ctx, api.InstrumentECR(testdouble.GenerateMockECRAPI(releaseMockParams(digest1, digest2)), nil), job.Id)
+		assert.NoError(t, err)
+
+		entries := logs.FilterMessage("release").All()
+		assert.Len(t, entries, 1)
+		fields := fieldsOf(entries[0])
+		assert.Equal(t, "default", fields["repository"])
+		assert.Equal(t, "release", fields["tag"])
+		assert.Equal(t, digest2, fields["digest"])
+		assert.Equal(t, digest1, fields["previous_digest"])
+		assert.Equal(t, "alice", fields["actor"])
+		assert.EqualValues(t, job.Id, fields["scheduled_release_id"])
+		// ECR API の呼び出しも debug で出力
+		assert.NotEmpty(t, logs.FilterMessage("ecr call").FilterField(zap.String("ecr_method", "PutImage")).All())
+	})
+}
+
+func TestServerLimits(t *testing.T) {
+	repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+		{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+	}, "")
+	assert.NoError(t, err)
+	setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}})
+	server := NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{
+		Port:         18080,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: time.Minute,
+		IdleTimeout:  2 * time.Minute,
+		MaxBodySize:  64,
+	})
+
+	t.Run("タイムアウトの設定", func(t *testing.T) {
+		assert.Equal(t, "0.0.0.0:18080", server.Addr)
+		assert.Equal(t, 5*time.Second, server.ReadHeaderTimeout)
+		assert.Equal(t, 5*time.Second, server.ReadTimeout)
+		assert.Equal(t, time.Minute, server.WriteTimeout)
+		assert.Equal(t, 2*time.Minute, server.IdleTimeout)
+	})
+
+	t.Run("リクエストボディの上限", func(t *testing.T) {
+		send := func(body io.Reader) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/images", body)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.Handler.ServeHTTP(w, req)
+			return w
+		}
+		large := fmt.Sprintf(`{"tag":"%s"}`, strings.Repeat("a", 100))
+
+		// Content-Length で判定
+		w := send(strings.NewReader(large))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Contains(t, w.Body.String(), `"request_id":`)
+		// Content-Length がない場合は読み込んだ量で判定
+		w = send(io.MultiReader(strings.NewReader(large)))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		// 上限以下のボディはそのまま Validation に渡す
+		w = send(strings.NewReader(`{"tag":"latest","digest":"sha256:0"}`))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		// 上限を超えていない読み込みの失敗は 413 にしない
+		w = send(io.MultiReader(strings.NewReader(`{"tag":`), iotest.ErrReader(errors.New("read timeout"))))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.NotContains(t, w.Body.String(), "上限")
+		// 接続が切れた場合は 499
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(http.MethodPost, "/images", iotest.ErrReader(context.Canceled)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		assert.Equal(t, api.StatusClientClosedRequest, w.Code)
+	})
+
+	t.Run("停止時の待ち時間（0 は完了するまで待つ）", func(t *testing.T) {
+		ctx, cancel := shutdownContext(time.Minute)
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+
+		ctx, cancel = shutdownContext(0)
+		defer cancel()
+		_, ok = ctx.Deadline()
+		assert.False(t, ok)
+		assert.NoError(t, ctx.Err())
+	})
+
+	t.Run("待ち受けに失敗した場合は終了せずにエラーを返す", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer listener.Close()
+		// 使用中のポート
+		err = serve(context.Background(), &http.Server{Addr: listener.Addr().String()}, zap.NewNop())
+		assert.Error(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		server := &http.Server{Addr: "127.0.0.1:0"}
+		cancel()
+		assert.NoError(t, serve(ctx, server, zap.NewNop()))
+		assert.NoError(t, server.Shutdown(context.Background()))
+	})
+}
+
+func TestReadiness(t *testing.T) {
+	repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+		{Name: "repository1", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+		{Name: "repository2", Uri: "000000000000.dkr.ecr.us-east-1.amazonaws.com/repository2"},
+	}, "repository1")
+	assert.NoError(t, err)
+	setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}})
+	authenticator, err := api.NewAuthenticator(api.AuthConfig{}, fmt.Sprintf("alice:%s", api.HashApiKey("alice-key")))
+	assert.NoError(t, err)
+	handler := NewGinSetReleaseTagServer(setReleaseTag, authenticator, ServerConfig{}).Handler
+	send := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	// リポジトリごとの確認回数と、アクセスできないリポジトリ
+	var checked []string
+	var checkedLock sync.Mutex
+	failing := map[string]bool{}
+	check := func(ctx context.Context, repository api.Repository) error {
+		// リポジトリごとに並行して呼ばれる
+		checkedLock.Lock()
+		defer checkedLock.Unlock()
+		checked = append(checked, repository.Name)
+		if failing[repository.Name] {
+			return errors.New("AccessDeniedException")
+		}
+		return nil
+	}
+
+	t.Run("死活監視は認証不要で ECR を確認しない", func(t *testing.T) {
+		setReleaseTag.Readiness = api.NewReadinessChecker(repositories, func(ctx context.Context, repository api.Repository) error {
+			t.Fatal("/healthz で ECR を確認しています")
+			return nil
+		}, time.Hour)
+		w := send("/healthz")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+	})
+
+	t.Run("すべてのリポジトリにアクセスできる場合は 200", func(t *testing.T) {
+		checked = nil
+		setReleaseTag.Readiness = api.NewReadinessChecker(repositories, check, time.Hour)
+		w := send("/readyz")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result api.Readiness
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, api.Ready, result.Status)
+		assert.Equal(t, []api.RepositoryReadiness{
+			{Name: "repository1", Ready: true},
+			{Name: "repository2", Ready: true},
+		}, result.Repositories)
+		assert.ElementsMatch(t, []string{"repository1", "repository2"}, checked)
+
+		// キャッシュの有効期間内は確認しない
+		failing["repository2"] = true
+		w = send("/readyz")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, checked, 2)
+		delete(failing, "repository2")
+	})
+
+	t.Run("アクセスできないリポジトリがある場合は 503", func(t *testing.T) {
+		checked = nil
+		failing["repository2"] = true
+		defer delete(failing, "repository2")
+		setReleaseTag.Readiness = api.NewReadinessChecker(repositories, check, 0)
+		w := send("/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		var result api.Readiness
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, api.NotReady, result.Status)
+		assert.True(t, result.Repositories[0].Ready)
+		assert.False(t, result.Repositories[1].Ready)
+		assert.Equal(t, "AccessDeniedException", aws.ToString(result.Repositories[1].Message))
+
+		// キャッシュの有効期間が過ぎていれば確認し直す
+		delete(failing, "repository2")
+		w = send("/readyz")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, checked, 4)
+	})
+}
+
+func TestImageCache(t *testing.T) {
+	images1 := []api.Image{{Tags: []string{"latest"}}}
+	images2 := []api.Image{{Tags: []string{"latest", "release"}}}
+	// ECR の呼び出し回数
+	var fetched int32
+	var fetchedLock sync.Mutex
+	fetchOf := func(images []api.Image) func(ctx context.Context) ([]api.Image, error) {
+		return func(ctx context.Context) ([]api.Image, error) {
+			fetchedLock.Lock()
+			fetched++
+			fetchedLock.Unlock()
+			return images, nil
+		}
+	}
+
+	t.Run("有効期間内はキャッシュを返す", func(t *testing.T) {
+		fetched = 0
+		cache := api.NewImageCache(time.Hour)
+		result, err := cache.Get(context.TODO(), "repository1", fetchOf(images1))
+		assert.NoError(t, err)
+		assert.False(t, result.Hit)
+		assert.Equal(t, time.Duration(0), result.Age)
+		assert.Equal(t, images1, result.Images)
+
+		result, err = cache.Get(context.TODO(), "repository1", fetchOf(images2))
+		assert.NoError(t, err)
+		assert.True(t, result.Hit)
+		assert.Equal(t, images1, result.Images)
+		assert.EqualValues(t, 1, fetched)
+
+		// リポジトリごとにキャッシュする
+		result, err = cache.Get(context.TODO(), "repository2", fetchOf(images2))
+		assert.NoError(t, err)
+		assert.False(t, result.Hit)
+		assert.EqualValues(t, 2, fetched)
+
+		// 無効化後は取得し直す
+		cache.Invalidate("repository1")
+		result, err = cache.Get(context.TODO(), "repository1", fetchOf(images2))
+		assert.NoError(t, err)
+		assert.False(t, result.Hit)
+		assert.Equal(t, images2, result.Images)
+		assert.EqualValues(t, 3, fetched)
+	})
+
+	t.Run("取得に失敗した場合はキャッシュしない", func(t *testing.T) {
+		cache := api.NewImageCache(time.Hour)
+		_, err := cache.Get(context.TODO(), "repository1", func(ctx context.Context) ([]api.Image, error) {
+			return nil, errors.New("ThrottlingException")
+		})
+		assert.Error(t, err)
+		result, err := cache.Get(context.TODO(), "repository1", fetchOf(images1))
+		assert.NoError(t, err)
+		assert.False(t, result.Hit)
+		assert.Equal(t, images1, result.Images)
+	})
+
+	t.Run("同時に取得する場合は ECR の呼び出しを 1 回にまとめる", func(t *testing.T) {
+		fetched = 0
+		cache := api.NewImageCache(time.Hour)
+		started := make(chan struct{})
+		release := make(chan struct{})
+		fetch := func(ctx context.Context) ([]api.Image, error) {
+			close(started)
+			<-release
+			return fetchOf(images1)(ctx)
+		}
+		var wg sync.WaitGroup
+		results := make([]api.CachedImages, 5)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[0], _ = cache.Get(context.TODO(), "repository1", fetch)
+		}()
+		<-started
+		for i := 1; i < len(results); i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _ = cache.Get(context.TODO(), "repository1", fetchOf(images2))
+			}(i)
+		}
+		// 後から来たリクエストが取得中の呼び出しに合流するまで待つ
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		assert.EqualValues(t, 1, fetched)
+		for _, v := range results {
+			assert.Equal(t, images1, v.Images)
+		}
+	})
+
+	t.Run("無効化前に始めた取得の結果はキャッシュしない", func(t *testing.T) {
+		fetched = 0
+		cache := api.NewImageCache(time.Hour)
+		started := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan api.CachedImages)
+		go func() {
+			result, _ := cache.Get(context.TODO(), "repository1", func(ctx context.Context) ([]api.Image, error) {
+				close(started)
+				<-release
+				return images1, nil
+			})
+			done <- result
+		}()
+		<-started
+		// 取得中にタグを付け替えた場合、無効化後のリクエストは取得中の呼び出しに合流しない
+		cache.Invalidate("repository1")
+		result, err := cache.Get(context.TODO(), "repository1", fetchOf(images2))
+		assert.NoError(t, err)
+		assert.Equal(t, images2, result.Images)
+		close(release)
+		assert.Equal(t, images1, (<-done).Images)
+
+		result, err = cache.Get(context.TODO(), "repository1", fetchOf(images1))
+		assert.NoError(t, err)
+		assert.True(t, result.Hit)
+		assert.Equal(t, images2, result.Images)
+	})
+
+	t.Run("TTL が 0 の場合はキャッシュしない", func(t *testing.T) {
+		fetched = 0
+		cache := api.NewImageCache(0)
+		assert.Nil(t, cache)
+		for i := 0; i < 2; i++ {
+			result, err := cache.Get(context.TODO(), "repository1", fetchOf(images1))
+			assert.NoError(t, err)
+			assert.False(t, result.Hit)
+		}
+		cache.Invalidate("repository1")
+		assert.EqualValues(t, 2, fetched)
+	})
+}
+
+func TestEcrClientProvider(t *testing.T) {
+	t.Run("リージョンごとに ECR クライアントを使い回す", func(t *testing.T) {
+		// 環境変数の認証情報のみを使う
+		t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+		t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+		t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+		factory, err := api.NewEcrClientFactory(context.TODO(), api.AwsConfig{})
+		assert.NoError(t, err)
+		client1, err := factory.Client("ap-northeast-1")
+		assert.NoError(t, err)
+		client2, err := factory.Client("ap-northeast-1")
+		assert.NoError(t, err)
+		client3, err := factory.Client("us-east-1")
+		assert.NoError(t, err)
+		assert.Same(t, client1, client2)
+		assert.NotSame(t, client1, client3)
+		assert.NoError(t, factory.CheckCredentials(context.TODO()))
+	})
+
+	t.Run("ECR クライアントの提供元が設定されていない場合", func(t *testing.T) {
+		repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+			{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+		}, "")
+		assert.NoError(t, err)
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}})
+		err = setReleaseTag.CheckRepositoryAccess(context.TODO(), repositories.Default())
+		assert.ErrorIs(t, err, api.ErrNoEcrClient)
+	})
+
+	t.Run("モックに差し替えて一覧取得・リリース（キャッシュの無効化）", func(t *testing.T) {
+		digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+		digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+		mock := testdouble.GenerateMockECRAPI(imagesMockParams(digest1, digest2))
+		// DescribeImages（一覧取得）の呼び出し回数
+		describeImages := 0
+		describe := mock.DescribeImagesAPI
+		mock.DescribeImagesAPI = func(ctx context.Context, params *ecr.DescribeImagesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
+			if params.ImageIds == nil {
+				describeImages++
+			}
+			return describe(ctx, params, optFns...)
+		}
+		repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+			{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+		}, "")
+		assert.NoError(t, err)
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{Repositories: repositories, TagNames: []string{"release"}, EcrClients: testdouble.MockECRClientProvider{API: mock}})
+		// 指定しなければキャッシュしない
+		assert.Nil(t, setReleaseTag.Images)
+		setReleaseTag = api.NewSetReleaseTag(api.SetReleaseTagOptions{
+			Repositories:  repositories,
+			TagNames:      []string{"release"},
+			EcrClients:    testdouble.MockECRClientProvider{API: mock},
+			ImageCacheTtl: time.Hour,
+		})
+		handler := NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{}).Handler
+		send := func(method string, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, "/images", strings.NewReader(body))
+			if body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+
+		w := send(http.MethodGet, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+		assert.Equal(t, "0", w.Header().Get("Age"))
+		assert.Contains(t, w.Body.String(), digest2)
+		w = send(http.MethodGet, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+		assert.Equal(t, 1, describeImages)
+
+		// リリース後はキャッシュを無効化して取得し直す
+		w = send(http.MethodPost, `{"tag":"latest"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "updated", w.Header().Get("X-Release-Status"))
+		assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+		assert.Equal(t, 2, describeImages)
+		w = send(http.MethodGet, "")
+		assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+		assert.Equal(t, 2, describeImages)
+	})
+}
+
+func TestTimeouts(t *testing.T) {
+	digest1 := "sha256:4d2653f861f1c4cb187f1a61f97b9af7adec9ec1986d8e253052cfa60fd7372f"
+	digest2 := "sha256:20b39162cb057eab7168652ab012ae3712f164bf2b4ef09e6541fca4ead3df62"
+	repositories, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+		{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"},
+	}, "")
+	assert.NoError(t, err)
+	// テスト終了時に応答しない ECR API を止める
+	stop := make(chan struct{})
+	defer close(stop)
+	// 呼び出されると started に通知し、context が終わるまで応答しない DescribeImages
+	hangingDescribeImages := func(started chan struct{}) testdouble.MockECRDescribeImagesAPI {
+		return func(ctx context.Context, params *ecr.DescribeImagesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
+			if started != nil {
+				close(started)
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-stop:
+				return nil, errors.New("stopped")
+			}
+		}
+	}
+	newServer := func(mock testdouble.MockECRAPI, ecrCallTimeout time.Duration, operationTimeout time.Duration) http.Handler {
+		setReleaseTag := api.NewSetReleaseTag(api.SetReleaseTagOptions{
+			Repositories:     repositories,
+			TagNames:         []string{"release"},
+			EcrClients:       testdouble.MockECRClientProvider{API: mock},
+			EcrCallTimeout:   ecrCallTimeout,
+			OperationTimeout: operationTimeout,
+		})
+		return NewGinSetReleaseTagServer(setReleaseTag, nil, ServerConfig{}).Handler
+	}
+	errorOf := func(w *httptest.ResponseRecorder) api.Error {
+		var body api.Error
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body
+	}
+
+	t.Run("ECR API の呼び出しがタイムアウトした場合は 504", func(t *testing.T) {
+		mock := testdouble.GenerateMockECRAPI(imagesMockParams(digest1, digest2))
+		mock.DescribeImagesAPI = hangingDescribeImages(nil)
+		handler := newServer(mock, 50*time.Millisecond, time.Minute)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/images", nil))
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+		assert.Contains(t, errorOf(w).Message, "処理が時間内に完了しませんでした")
+		assert.Contains(t, errorOf(w).Message, "ECR API（DescribeImages）の呼び出しが 50ms 以内に完了しませんでした")
+	})
+
+	t.Run("リクエスト全体がタイムアウトした場合は 504", func(t *testing.T) {
+		mock := testdouble.GenerateMockECRAPI(imagesMockParams(digest1, digest2))
+		mock.DescribeImagesAPI = hangingDescribeImages(nil)
+		handler := newServer(mock, 0, 50*time.Millisecond)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/images", nil))
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+		assert.Contains(t, errorOf(w).Message, "処理が時間内に完了しませんでした")
+	})
+
+	t.Run("呼び出し元が接続を切った場合は 499", func(t *testing.T) {
+		started := make(chan struct{})
+		mock := testdouble.GenerateMockECRAPI(imagesMockParams(digest1, digest2))
+		mock.DescribeImagesAPI = hangingDescribeImages(started)
+		handler := newServer(mock, time.Minute, time.Minute)
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-started
+			cancel()
+		}()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/images", nil).WithContext(ctx))
+		assert.Equal(t, api.StatusClientClosedRequest, w.Code)
+		assert.Contains(t, errorOf(w).Message, "呼び出し元がリクエストを取り消しました")
+	})
+
+	t.Run("タグの付け替え中に接続を切っても付け替えは止めない", func(t *testing.T) {
+		mock := testdouble.GenerateMockECRAPI(imagesMockParams(digest1, digest2))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		put := mock.PutImageAPI
+		var putErr error
+		mock.PutImageAPI = func(putCtx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error) {
+			cancel()
+			putErr = putCtx.Err()
+			return put(putCtx, params, optFns...)
+		}
+		handler := newServer(mock, time.Minute, time.Minute)
+		req := httptest.NewRequest(http.MethodPost, "/images", strings.NewReader(`{"tag":"latest"}`)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.NoError(t, putErr)
+		assert.Equal(t, "updated", w.Header().Get("X-Release-Status"))
+		// 付け替え後の一覧取得は取り消す
+		assert.Equal(t, api.StatusClientClosedRequest, w.Code)
+	})
+
+	t.Run("接続を切った後はタグを付け替えない", func(t *testing.T) {
+		mock := testdouble.GenerateMockECRAPI(imagesMockParams(digest1, digest2))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// 付与するタグの確認中に接続を切る
+		describe := mock.DescribeRepositoriesAPI
+		mock.DescribeRepositoriesAPI = func(ctx context.Context, params *ecr.DescribeRepositoriesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
+			cancel()
+			return describe(context.TODO(), params, optFns...)
+		}
+		put := false
+		mock.PutImageAPI = func(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error) {
+			put = true
+			return &ecr.PutImageOutput{}, nil
+		}
+		handler := newServer(mock, time.Minute, time.Minute)
+		req := httptest.NewRequest(http.MethodPost, "/images", strings.NewReader(`{"tag":"latest"}`)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, api.StatusClientClosedRequest, w.Code)
+		assert.False(t, put)
+	})
+}
+
+func TestParseRepositoryUri(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		expected api.RepositoryUri
+		reason   string
+	}{
+		{
+			name:     "通常のリポジトリ",
+			uri:      "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1",
+			expected: api.RepositoryUri{RegistryId: "000000000000", Region: "ap-northeast-1", Partition: "aws", Name: "repository1"},
+		},
+		{
+			name:     "名前空間付きのリポジトリ",
+			uri:      "123456789012.dkr.ecr.us-east-1.amazonaws.com/team/service/api",
+			expected: api.RepositoryUri{RegistryId: "123456789012", Region: "us-east-1", Partition: "aws", Name: "team/service/api"},
+		},
+		{
+			name:     "中国リージョン",
+			uri:      "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn/repository1",
+			expected: api.RepositoryUri{RegistryId: "123456789012", Region: "cn-north-1", Partition: "aws-cn", Name: "repository1"},
+		},
+		{
+			name:     "GovCloud の FIPS エンドポイント",
+			uri:      "123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com/my_app.web-1",
+			expected: api.RepositoryUri{RegistryId: "123456789012", Region: "us-gov-west-1", Partition: "aws-us-gov", Fips: true, Name: "my_app.web-1"},
+		},
+		{
+			name:     "ホスト名の大文字",
+			uri:      "123456789012.DKR.ECR.EU-WEST-1.AMAZONAWS.COM/repository1",
+			expected: api.RepositoryUri{RegistryId: "123456789012", Region: "eu-west-1", Partition: "aws", Name: "repository1"},
+		},
+		{name: "空", uri: "", reason: "リポジトリ名がありません"},
+		{name: "リポジトリ名なし", uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com", reason: "リポジトリ名がありません"},
+		{name: "リポジトリ名が空", uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/", reason: "リポジトリ名がありません"},
+		{name: "スキーム付き", uri: "https://000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1", reason: "スキーム"},
+		{name: "ECR 以外のレジストリ", uri: "ghcr.io/owner/repository1", reason: "ECR のレジストリではありません"},
+		{name: "ECR 以外のサービス", uri: "000000000000.dkr.ecs.ap-northeast-1.amazonaws.com/repository1", reason: "ECR のレジストリではありません"},
+		{name: "未知のドメイン", uri: "000000000000.dkr.ecr.ap-northeast-1.example.com/repository1", reason: "ドメイン"},
+		{name: "ポート付き", uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com:443/repository1", reason: "ドメイン"},
+		{name: "レジストリ ID の桁数", uri: "00000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1", reason: "12 桁"},
+		{name: "リージョンの形式", uri: "000000000000.dkr.ecr.tokyo.amazonaws.com/repository1", reason: "リージョン"},
+		{name: "タグ付き", uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1:latest", reason: "タグ・ダイジェスト"},
+		{name: "ダイジェスト付き", uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1@sha256:0", reason: "タグ・ダイジェスト"},
+		{name: "リポジトリ名が短い", uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/a", reason: "2 〜 256 文字"},
+		{name: "リポジトリ名が長い", uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/" + strings.Repeat("a", 257), reason: "2 〜 256 文字"},
+		{name: "リポジトリ名の大文字", uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/Repository1", reason: "使えない文字"},
+		{name: "空の名前空間", uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/team//api", reason: "使えない文字"},
+		{name: "末尾のスラッシュ", uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/team/", reason: "使えない文字"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, err := api.ParseRepositoryUri(tt.uri)
+			if tt.reason == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, uri)
+				return
+			}
+			assert.ErrorIs(t, err, api.ErrInvalidRepositoryUri)
+			var uriErr *api.RepositoryUriError
+			if assert.ErrorAs(t, err, &uriErr) {
+				assert.Equal(t, tt.uri, uriErr.Uri)
+				assert.Contains(t, uriErr.Reason, tt.reason)
+			}
+		})
+	}
+
+	t.Run("URI の組み立て", func(t *testing.T) {
+		uri, err := api.ParseRepositoryUri("123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com/team/api")
+		assert.NoError(t, err)
+		assert.Equal(t, "123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com", uri.Host())
+		assert.Equal(t, "123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com/team/api", uri.String())
+	})
+
+	t.Run("起動時に URI を確認", func(t *testing.T) {
+		_, err := api.NewRepositoryRegistry([]api.RepositoryConfig{
+			{Name: "default", Uri: "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com"},
+		}, "")
+		assert.ErrorIs(t, err, api.ErrInvalidRepositoryUri)
+		assert.Contains(t, err.Error(), "リポジトリ（default）")
+	})
+
+	t.Run("不正な URI では ECR を呼び出さずにエラー", func(t *testing.T) {
+		_, err := api.ImageList(context.TODO(), testdouble.GenerateMockECRAPI(testdouble.MockECRParams{}), "repository1", 1000, 0)
+		assert.ErrorIs(t, err, api.ErrInvalidRepositoryUri)
+		_, err = api.SetTag(context.TODO(), testdouble.GenerateMockECRAPI(testdouble.MockECRParams{}), "000000000000.dkr.ecr/repository1", []string{"release"}, types.ImageIdentifier{ImageTag: aws.String("latest")})
+		assert.ErrorIs(t, err, api.ErrInvalidRepositoryUri)
+	})
+}
+
+// go test -fuzz=FuzzParseRepositoryUri で実行する
+func FuzzParseRepositoryUri(f *testing.F) {
+	f.Add("000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1")
+	f.Add("123456789012.dkr.ecr.us-east-1.amazonaws.com/team/service/api")
+	f.Add("123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn/repository1")
+	f.Add("123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com/repository1")
+	f.Add("123456789012.dkr.ecr.us-iso-east-1.c2s.ic.gov/repository1")
+	f.Add("000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1:latest")
+	f.Add("repository1")
+	f.Add("/")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, s string) {
+		uri, err := api.ParseRepositoryUri(s)
+		if err != nil {
+			if !errors.Is(err, api.ErrInvalidRepositoryUri) {
+				t.Fatalf("型付きのエラーではありません : %v", err)
+			}
+			return
+		}
+		if uri.RegistryId == "" || uri.Region == "" || uri.Partition == "" || uri.Name == "" {
+			t.Fatalf("空の項目があります : %#v", uri)
+		}
+		// 組み立て直した URI を分解すると同じ結果になる
+		again, err := api.ParseRepositoryUri(uri.String())
+		if err != nil {
+			t.Fatalf("組み立て直した URI（%s）を分解できません : %v", uri.String(), err)
+		}
+		if again != uri {
+			t.Fatalf("組み立て直した URI の分解結果が異なります : %#v / %#v", uri, again)
+		}
+	})
+}
+
+func TestConfig(t *testing.T) {
+	repositoryUri := "000000000000.dkr.ecr.ap-northeast-1.amazonaws.com/repository1"
+	writeConfig := func(t *testing.T, config string) string {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(path, []byte(config), 0600)
+		assert.NoError(t, err)
+		return path
+	}
+	env := func(values map[string]string) func(key string) (string, bool) {
+		return func(key string) (string, bool) {
+			value, ok := values[key]
+			return value, ok
+		}
+	}
+
+	t.Run("省略した項目はデフォルト値", func(t *testing.T) {
+		path := writeConfig(t, "repositories:\n  - name: app1\n    uri: "+repositoryUri+"\n")
+		cfg, err := api.ReadConfig(path, env(nil))
+		assert.NoError(t, err)
+		assert.NoError(t, cfg.Validate())
+		assert.Equal(t, 18080, cfg.Server.Port)
+		assert.Equal(t, []string{"release"}, cfg.Tags)
+		assert.Equal(t, api.DefaultOperationTimeout, cfg.Server.OperationTimeout)
+		assert.Equal(t, api.DefaultImageCacheTtl, cfg.Images.CacheTtl)
+		assert.True(t, cfg.Features.Metrics)
+		assert.True(t, cfg.Features.ReleaseHistory)
+		assert.True(t, cfg.Features.Scheduler)
+	})
+
+	t.Run("環境変数で上書き", func(t *testing.T) {
+		path := writeConfig(t, `
+tag_name: prod
+repositories:
+  - name: app1
+    uri: `+repositoryUri+`
+server:
+  port: 8080
+aws:
+  region: ap-northeast-1
+features:
+  metrics: true
+`)
+		cfg, err := api.ReadConfig(path, env(map[string]string{
+			"SET_RELEASE_TAG_SERVER_PORT":              "9090",
+			"SET_RELEASE_TAG_SERVER_OPERATION_TIMEOUT": "45s",
+			"SET_RELEASE_TAG_AWS_PROFILE":              "release",
+			"SET_RELEASE_TAG_TAGS":                     "release, release-{date}-{seq}",
+			"SET_RELEASE_TAG_FEATURES_METRICS":         "false",
+			"SET_RELEASE_TAG_API_KEYS":                 fmt.Sprintf("alice:%s", api.HashApiKey("alice-key")),
+		}))
+		assert.NoError(t, err)
+		assert.NoError(t, cfg.Validate())
+		assert.Equal(t, "prod", cfg.TagName)
+		assert.Equal(t, []string{"release", "release-{date}-{seq}"}, cfg.Tags)
+		assert.Equal(t, 9090, cfg.Server.Port)
+		assert.Equal(t, 45*time.Second, cfg.Server.OperationTimeout)
+		assert.Equal(t, "ap-northeast-1", cfg.Aws.Region)
+		assert.Equal(t, "release", cfg.Aws.Profile)
+		assert.False(t, cfg.Features.Metrics)
+		assert.NotEmpty(t, cfg.ApiKeys)
+	})
+
+	t.Run("すべての誤りをまとめて返す", func(t *testing.T) {
+		path := writeConfig(t, `
+tags: ["release/{version}"]
+immutable_strategy: skip
+repositories:
+  - name: app1
+    uri: https://`+repositoryUri+`
+  - name: app1
+    uri: `+repositoryUri+`
+policies:
+  - repositories: [app2]
+    groups: [developers]
+    permissions: [deploy]
+webhooks:
+  - url: ftp://example.com/hook
+server:
+  prot: 8080
+  read_timeout: -1s
+images:
+  page_size: 2000
+log:
+  level: verbose
+`)
+		cfg, err := api.ReadConfig(path, env(map[string]string{
+			"SET_RELEASE_TAG_SERVER_PORT":   "http",
+			"SET_RELEASE_TAG_AUTH_JWT":      "jwks.json",
+			"SET_RELEASE_TAG_REPOSITORIES":  repositoryUri,
+			"SET_RELEASE_TAG_APPROVAL_TTL":  "1 day",
+			"SET_RELEASE_TAG_HISTORY_PATH":  "",
+			"SET_RELEASE_TAG_FEATURES_NONE": "true",
+		}))
+		assert.NoError(t, err)
+		err = cfg.Validate()
+		var configErr *api.ConfigError
+		assert.True(t, errors.As(err, &configErr))
+		assert.Equal(t, path, configErr.Path)
+		for _, v := range []string{
+			"prot",
+			"SET_RELEASE_TAG_SERVER_PORT",
+			"SET_RELEASE_TAG_AUTH_JWT",
+			"SET_RELEASE_TAG_REPOSITORIES",
+			"SET_RELEASE_TAG_APPROVAL_TTL",
+			"tags",
+			"immutable_strategy",
+			"スキーム",
+			"重複",
+			"権限（deploy）",
+			"リポジトリ（app2）",
+			"Webhook（1 番目）",
+			"server.read_timeout",
+			"images.page_size",
+			"history.path",
+			"log",
+		} {
+			assert.Contains(t, err.Error(), v)
+		}
+		assert.Len(t, configErr.Problems, 16)
+		assert.True(t, strings.HasPrefix(err.Error(), fmt.Sprintf("設定ファイル（%s）に 16 件の誤りがあります\n  - ", path)))
+	})
+
+	t.Run("承認が必要なリポジトリは認証の設定が必要", func(t *testing.T) {
+		path := writeConfig(t, "repositories:\n  - name: app1\n    uri: "+repositoryUri+"\n    require_approval: true\n")
+		cfg, err := api.ReadConfig(path, env(nil))
+		assert.NoError(t, err)
+		err = cfg.Validate()
+		assert.ErrorContains(t, err, "リポジトリ（app1）の require_approval")
+
+		cfg, err = api.ReadConfig(path, env(map[string]string{
+			"SET_RELEASE_TAG_API_KEYS": "alice:" + api.HashApiKey("alice-key"),
+		}))
+		assert.NoError(t, err)
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("YAML として解釈できない場合はエラー", func(t *testing.T) {
+		_, err := api.ReadConfig(writeConfig(t, "repositories: [\n"), env(nil))
+		assert.Error(t, err)
+		_, err = api.ReadConfig(filepath.Join(t.TempDir(), "missing.yaml"), env(nil))
+		assert.Error(t, err)
+	})
+
+	t.Run("コマンドラインパラメータは設定ファイル・環境変数より優先", func(t *testing.T) {
+		path := writeConfig(t, "repositories:\n  - name: app1\n    uri: "+repositoryUri+"\nserver:\n  port: 8080\n  read_timeout: 5s\n")
+		cmd, err := parseCommandLine([]string{"-config", path, "-port=7070"}, io.Discard)
+		assert.NoError(t, err)
+		assert.False(t, cmd.validateOnly)
+		cfg, err := cmd.loadConfig(env(map[string]string{
+			"SET_RELEASE_TAG_SERVER_PORT":         "9090",
+			"SET_RELEASE_TAG_SERVER_IDLE_TIMEOUT": "10s",
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, 7070, cfg.Server.Port)
+		assert.Equal(t, 5*time.Second, cfg.Server.ReadTimeout)
+		assert.Equal(t, 10*time.Second, cfg.Server.IdleTimeout)
+		assert.Equal(t, "app1", cfg.Repositories[0].Name)
+	})
+
+	t.Run("設定ファイルがなければリポジトリ URI・タグをコマンドラインパラメータで指定", func(t *testing.T) {
+		cmd, err := parseCommandLine([]string{"-port=7070", repositoryUri, "prod"}, io.Discard)
+		assert.NoError(t, err)
+		cfg, err := cmd.loadConfig(env(nil))
+		assert.NoError(t, err)
+		assert.Equal(t, "default", cfg.DefaultRepository)
+		assert.Equal(t, repositoryUri, cfg.Repositories[0].Uri)
+		assert.Equal(t, []string{"prod"}, cfg.Tags)
+
+		// リポジトリの指定がない場合は panic せずにエラー
+		cmd, err = parseCommandLine(nil, io.Discard)
+		assert.NoError(t, err)
+		_, err = cmd.loadConfig(env(nil))
+		assert.ErrorContains(t, err, "リポジトリの指定がありません")
+	})
+
+	t.Run("validate-config サブコマンド", func(t *testing.T) {
+		path := writeConfig(t, "repositories:\n  - name: app1\n    uri: "+repositoryUri+"\n")
+		for _, args := range [][]string{
+			{"validate-config", "-config", path},
+			{"-config", path, "validate-config"},
+		} {
+			cmd, err := parseCommandLine(args, io.Discard)
+			assert.NoError(t, err)
+			assert.True(t, cmd.validateOnly)
+			var stdout, stderr strings.Builder
+			assert.Equal(t, 0, validateConfig(cmd, env(nil), &stdout, &stderr))
+			assert.Contains(t, stdout.String(), "設定に誤りはありません")
+			assert.Empty(t, stderr.String())
+		}
+
+		cmd, err := parseCommandLine([]string{"validate-config", "-config", path, "-page-size=0", "-log-format=text"}, io.Discard)
+		assert.NoError(t, err)
+		var stdout, stderr strings.Builder
+		assert.Equal(t, 1, validateConfig(cmd, env(nil), &stdout, &stderr))
+		assert.Empty(t, stdout.String())
+		assert.Contains(t, stderr.String(), "2 件の誤りがあります")
+		assert.Contains(t, stderr.String(), "images.page_size（0）")
+		assert.Contains(t, stderr.String(), "ログの形式（text）")
+
+		_, err = parseCommandLine([]string{"validate-config", "-unknown"}, io.Discard)
+		assert.Error(t, err)
+	})
+}
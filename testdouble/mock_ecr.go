@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
 	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/hmatsu47/set-release-tag-api/api"
 )
 
 // モックパラメーター
@@ -16,23 +17,37 @@ type ECRParams struct {
 	AttachTagName   string
 	SelectedTagName string
 	Images          []types.Image
+	// 現在 AttachTagName が付いているイメージ（なければ空）
+	ReleasedImages []types.Image
+	// AttachTagName 以外に付与するタグ（どのイメージにも付いていない前提）
+	AdditionalTagNames []string
+	// PutImage が失敗するタグ
+	FailTagNames []string
+	// リポジトリのタグの変更可否（省略時は MUTABLE）
+	ImageTagMutability types.ImageTagMutability
 }
 
 // モック生成用
 type MockECRParams struct {
 	ECRParams ECRParams
+	// DescribeImages を複数ページで返す場合に指定（指定時は ECRParams.ImageDetails の代わりに使用）
+	ImageDetailsPages [][]types.ImageDetail
 }
 
 // モック化
 type MockECRAPI struct {
-	DescribeImagesAPI MockECRDescribeImagesAPI
-	BatchGetImageAPI  MockECRBatchGetImageAPI
-	PutImageAPI       MockECRPutImageAPI
+	DescribeImagesAPI       MockECRDescribeImagesAPI
+	BatchGetImageAPI        MockECRBatchGetImageAPI
+	PutImageAPI             MockECRPutImageAPI
+	BatchDeleteImageAPI     MockECRBatchDeleteImageAPI
+	DescribeRepositoriesAPI MockECRDescribeRepositoriesAPI
 }
 
 type MockECRDescribeImagesAPI func(ctx context.Context, params *ecr.DescribeImagesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error)
 type MockECRBatchGetImageAPI func(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error)
 type MockECRPutImageAPI func(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error)
+type MockECRBatchDeleteImageAPI func(ctx context.Context, params *ecr.BatchDeleteImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error)
+type MockECRDescribeRepositoriesAPI func(ctx context.Context, params *ecr.DescribeRepositoriesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error)
 
 func (m MockECRAPI) DescribeImages(ctx context.Context, params *ecr.DescribeImagesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
 	return m.DescribeImagesAPI(ctx, params, optFns...)
@@ -45,3 +60,20 @@ func (m MockECRAPI) BatchGetImage(ctx context.Context, params *ecr.BatchGetImage
 func (m MockECRAPI) PutImage(ctx context.Context, params *ecr.PutImageInput, optFns ...func(*ecr.Options)) (*ecr.PutImageOutput, error) {
 	return m.PutImageAPI(ctx, params, optFns...)
 }
+
+func (m MockECRAPI) BatchDeleteImage(ctx context.Context, params *ecr.BatchDeleteImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error) {
+	return m.BatchDeleteImageAPI(ctx, params, optFns...)
+}
+
+func (m MockECRAPI) DescribeRepositories(ctx context.Context, params *ecr.DescribeRepositoriesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
+	return m.DescribeRepositoriesAPI(ctx, params, optFns...)
+}
+
+// リージョンによらず同じモックを返す ECR クライアントの提供元
+type MockECRClientProvider struct {
+	API api.ECRAPI
+}
+
+func (p MockECRClientProvider) Client(region string) (api.ECRAPI, error) {
+	return p.API, nil
+}
@@ -3,18 +3,22 @@ package testdouble
 import (
 	"context"
 	"errors"
+	"strconv"
 
 	// "fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
 )
 
 func GenerateMockECRAPI(mockParams MockECRParams) MockECRAPI {
 	return MockECRAPI{
-		DescribeImagesAPI: GenerateMockECRDescribeImagesAPI(mockParams),
-		BatchGetImageAPI:  GenerateMockECRBatchGetImageAPI(mockParams),
-		PutImageAPI:       GenerateMockECRPutImageAPI(mockParams),
+		DescribeImagesAPI:       GenerateMockECRDescribeImagesAPI(mockParams),
+		BatchGetImageAPI:        GenerateMockECRBatchGetImageAPI(mockParams),
+		PutImageAPI:             GenerateMockECRPutImageAPI(mockParams),
+		BatchDeleteImageAPI:     GenerateMockECRBatchDeleteImageAPI(mockParams),
+		DescribeRepositoriesAPI: GenerateMockECRDescribeRepositoriesAPI(mockParams),
 	}
 }
 
@@ -23,28 +27,142 @@ func GenerateMockECRDescribeImagesAPI(mockParams MockECRParams) MockECRDescribeI
 		// fmt.Printf("MockECRDescribeImagesAPI(Expect) : %d / %s / %s\n", mockParams.ECRParams.MaxResults, mockParams.ECRParams.RegistryId, mockParams.ECRParams.RepositoryName)
 		// fmt.Printf("MockECRDescribeImagesAPI(Real) :   %d / %s / %s\n", aws.ToInt32(params.MaxResults), aws.ToString(params.RegistryId), aws.ToString(params.RepositoryName))
 
-		if params.MaxResults == nil || aws.ToInt32(params.MaxResults) != mockParams.ECRParams.MaxResults {
-			return nil, errors.New("DescribeImagesを呼び出すときのMaxResultsの指定が間違っています")
-		}
 		if params.RegistryId == nil || aws.ToString(params.RegistryId) != mockParams.ECRParams.RegistryId {
 			return nil, errors.New("DescribeImagesを呼び出すときのRegistryIdの指定が間違っています")
 		}
 		if params.RepositoryName == nil || aws.ToString(params.RepositoryName) != mockParams.ECRParams.RepositoryName {
 			return nil, errors.New("DescribeImagesを呼び出すときのRepositoryNameの指定が間違っています")
 		}
+		if params.ImageIds != nil {
+			// イメージ指定の場合は MaxResults を指定できない
+			if params.MaxResults != nil || len(params.ImageIds) != 1 {
+				return nil, errors.New("DescribeImagesを呼び出すときのImageIdsの指定が間違っています")
+			}
+			imageDetail, ok := findImageDetail(mockParams, params.ImageIds[0])
+			if !ok {
+				return nil, &types.ImageNotFoundException{Message: aws.String("The image requested does not exist in the specified repository.")}
+			}
+			detailOutput := &ecr.DescribeImagesOutput{
+				ImageDetails: []types.ImageDetail{imageDetail},
+			}
+			return detailOutput, nil
+		}
+		if params.MaxResults == nil || aws.ToInt32(params.MaxResults) != mockParams.ECRParams.MaxResults {
+			return nil, errors.New("DescribeImagesを呼び出すときのMaxResultsの指定が間違っています")
+		}
+		if len(mockParams.ImageDetailsPages) == 0 {
+			detailOutput := &ecr.DescribeImagesOutput{
+				ImageDetails: mockParams.ECRParams.ImageDetails,
+			}
+			return detailOutput, nil
+		}
+
+		// 複数ページの場合は NextToken にページ番号を入れて返す
+		page := 0
+		if params.NextToken != nil {
+			var err error
+			page, err = strconv.Atoi(aws.ToString(params.NextToken))
+			if err != nil || page < 1 || page >= len(mockParams.ImageDetailsPages) {
+				return nil, errors.New("DescribeImagesを呼び出すときのNextTokenの指定が間違っています")
+			}
+		}
 		detailOutput := &ecr.DescribeImagesOutput{
-			ImageDetails: mockParams.ECRParams.ImageDetails,
+			ImageDetails: mockParams.ImageDetailsPages[page],
+		}
+		if page+1 < len(mockParams.ImageDetailsPages) {
+			detailOutput.NextToken = aws.String(strconv.Itoa(page + 1))
 		}
 		return detailOutput, nil
 	})
 }
 
+// タグまたはダイジェストが一致するイメージ詳細を検索
+func findImageDetail(mockParams MockECRParams, imageId types.ImageIdentifier) (types.ImageDetail, bool) {
+	imageDetails := mockParams.ECRParams.ImageDetails
+	for _, page := range mockParams.ImageDetailsPages {
+		imageDetails = append(imageDetails, page...)
+	}
+	for _, v := range imageDetails {
+		if imageId.ImageDigest != nil && aws.ToString(v.ImageDigest) != aws.ToString(imageId.ImageDigest) {
+			continue
+		}
+		if imageId.ImageTag != nil {
+			found := false
+			for _, tag := range v.ImageTags {
+				if tag == aws.ToString(imageId.ImageTag) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		return v, true
+	}
+	return types.ImageDetail{}, false
+}
+
+// ダイジェストが一致するイメージを検索
+func findImageByDigest(mockParams MockECRParams, digest string) (types.Image, bool) {
+	for _, images := range [][]types.Image{mockParams.ECRParams.Images, mockParams.ECRParams.ReleasedImages} {
+		for _, v := range images {
+			if v.ImageId != nil && aws.ToString(v.ImageId.ImageDigest) == digest {
+				return v, true
+			}
+		}
+	}
+	return types.Image{}, false
+}
+
+// マニフェストとメディアタイプが一致するイメージがあるか
+func hasImageManifest(mockParams MockECRParams, manifest string, mediaType string) bool {
+	for _, images := range [][]types.Image{mockParams.ECRParams.Images, mockParams.ECRParams.ReleasedImages} {
+		for _, v := range images {
+			if aws.ToString(v.ImageManifest) == manifest && aws.ToString(v.ImageManifestMediaType) == mediaType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// タグが一覧に含まれているか
+func containsTag(tagNames []string, tagName string) bool {
+	for _, v := range tagNames {
+		if v == tagName {
+			return true
+		}
+	}
+	return false
+}
+
+// イメージのメディアタイプがすべて受け付けるメディアタイプに含まれているか
+func acceptsMediaType(acceptedMediaTypes []string, images []types.Image) bool {
+	for _, v := range images {
+		if v.ImageManifestMediaType == nil {
+			continue
+		}
+		found := false
+		for _, mediaType := range acceptedMediaTypes {
+			if mediaType == aws.ToString(v.ImageManifestMediaType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 func GenerateMockECRBatchGetImageAPI(mockParams MockECRParams) MockECRBatchGetImageAPI {
 	return MockECRBatchGetImageAPI(func(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error) {
 		// fmt.Printf("MockECRBatchGetImageAPI(Expect) : %d / %s / %s\n", 1, mockParams.ECRParams.RegistryId, mockParams.ECRParams.RepositoryName)
 		// fmt.Printf("MockECRBatchGetImageAPI(Real) :   %d / %s / %s\n", len(params.ImageIds), aws.ToString(params.RegistryId), aws.ToString(params.RepositoryName))
 
-		if params.ImageIds == nil || len(params.ImageIds) != 1 || params.ImageIds[0].ImageTag == nil || aws.ToString(params.ImageIds[0].ImageTag) != mockParams.ECRParams.SelectedTagName {
+		if params.ImageIds == nil || len(params.ImageIds) != 1 {
 			return nil, errors.New("BatchGetImageを呼び出すときのImageIdsの指定が間違っています")
 		}
 		if params.RegistryId == nil || aws.ToString(params.RegistryId) != mockParams.ECRParams.RegistryId {
@@ -54,10 +172,57 @@ func GenerateMockECRBatchGetImageAPI(mockParams MockECRParams) MockECRBatchGetIm
 			return nil, errors.New("BatchGetImageを呼び出すときのRepositoryNameの指定が間違っています")
 		}
 
-		batchOutput := &ecr.BatchGetImageOutput{
-			Images: mockParams.ECRParams.Images,
+		if len(params.AcceptedMediaTypes) == 0 {
+			return nil, errors.New("BatchGetImageを呼び出すときのAcceptedMediaTypesの指定が間違っています")
+		}
+
+		imageId := params.ImageIds[0]
+		notFound := &ecr.BatchGetImageOutput{
+			Failures: []types.ImageFailure{
+				{
+					FailureCode:   types.ImageFailureCodeImageNotFound,
+					FailureReason: aws.String("Requested image not found"),
+					ImageId:       &imageId,
+				},
+			},
+		}
+		switch {
+		case imageId.ImageTag != nil && aws.ToString(imageId.ImageTag) == mockParams.ECRParams.SelectedTagName:
+			// 選択したタグ
+			if !acceptsMediaType(params.AcceptedMediaTypes, mockParams.ECRParams.Images) {
+				return nil, errors.New("BatchGetImageを呼び出すときのAcceptedMediaTypesにイメージのメディアタイプが含まれていません")
+			}
+			batchOutput := &ecr.BatchGetImageOutput{
+				Images: mockParams.ECRParams.Images,
+			}
+			return batchOutput, nil
+		case imageId.ImageTag != nil && aws.ToString(imageId.ImageTag) == mockParams.ECRParams.AttachTagName:
+			// 付け替え前のリリースタグ
+			if len(mockParams.ECRParams.ReleasedImages) == 0 {
+				return notFound, nil
+			}
+			batchOutput := &ecr.BatchGetImageOutput{
+				Images: mockParams.ECRParams.ReleasedImages,
+			}
+			return batchOutput, nil
+		case imageId.ImageTag != nil && containsTag(mockParams.ECRParams.AdditionalTagNames, aws.ToString(imageId.ImageTag)):
+			// 追加で付与するタグ（まだどのイメージにも付いていない）
+			return notFound, nil
+		case imageId.ImageTag == nil && imageId.ImageDigest != nil:
+			// ダイジェスト指定
+			image, ok := findImageByDigest(mockParams, aws.ToString(imageId.ImageDigest))
+			if !ok {
+				return notFound, nil
+			}
+			if !acceptsMediaType(params.AcceptedMediaTypes, []types.Image{image}) {
+				return nil, errors.New("BatchGetImageを呼び出すときのAcceptedMediaTypesにイメージのメディアタイプが含まれていません")
+			}
+			batchOutput := &ecr.BatchGetImageOutput{
+				Images: []types.Image{image},
+			}
+			return batchOutput, nil
 		}
-		return batchOutput, nil
+		return nil, errors.New("BatchGetImageを呼び出すときのImageIdsの指定が間違っています")
 	})
 }
 
@@ -66,8 +231,8 @@ func GenerateMockECRPutImageAPI(mockParams MockECRParams) MockECRPutImageAPI {
 		// fmt.Printf("MockECRPutImageAPI(Expect) : %s / %s / %s / %s\n", aws.ToString(mockParams.ECRParams.Images[0].ImageManifest), mockParams.ECRParams.RegistryId, mockParams.ECRParams.RepositoryName, mockParams.ECRParams.AttachTagName)
 		// fmt.Printf("MockECRPutImageAPI(Real) :   %s / %s / %s / %s\n", aws.ToString(params.ImageManifest), aws.ToString(params.RegistryId), aws.ToString(params.RepositoryName), aws.ToString(params.ImageTag))
 
-		if params.ImageManifest == nil || aws.ToString(params.ImageManifest) != aws.ToString(mockParams.ECRParams.Images[0].ImageManifest) {
-			return nil, errors.New("PutImageを呼び出すときのImageManifestの指定が間違っています")
+		if params.ImageManifest == nil || !hasImageManifest(mockParams, aws.ToString(params.ImageManifest), aws.ToString(params.ImageManifestMediaType)) {
+			return nil, errors.New("PutImageを呼び出すときのImageManifest・ImageManifestMediaTypeの指定が間違っています")
 		}
 		if params.RegistryId == nil || aws.ToString(params.RegistryId) != mockParams.ECRParams.RegistryId {
 			return nil, errors.New("PutImageを呼び出すときのRegistryIdの指定が間違っています")
@@ -75,9 +240,12 @@ func GenerateMockECRPutImageAPI(mockParams MockECRParams) MockECRPutImageAPI {
 		if params.RepositoryName == nil || aws.ToString(params.RepositoryName) != mockParams.ECRParams.RepositoryName {
 			return nil, errors.New("PutImageを呼び出すときのRepositoryNameの指定が間違っています")
 		}
-		if params.ImageTag == nil || aws.ToString(params.ImageTag) != mockParams.ECRParams.AttachTagName {
+		if params.ImageTag == nil || (aws.ToString(params.ImageTag) != mockParams.ECRParams.AttachTagName && !containsTag(mockParams.ECRParams.AdditionalTagNames, aws.ToString(params.ImageTag))) {
 			return nil, errors.New("PutImageを呼び出すときのImageTagの指定が間違っています")
 		}
+		if containsTag(mockParams.ECRParams.FailTagNames, aws.ToString(params.ImageTag)) {
+			return nil, &types.InvalidParameterException{Message: aws.String("Invalid parameter at 'imageTag'")}
+		}
 
 		PutImageOutput := &ecr.PutImageOutput{
 			Image: &mockParams.ECRParams.Images[0],
@@ -85,3 +253,67 @@ func GenerateMockECRPutImageAPI(mockParams MockECRParams) MockECRPutImageAPI {
 		return PutImageOutput, nil
 	})
 }
+
+func GenerateMockECRBatchDeleteImageAPI(mockParams MockECRParams) MockECRBatchDeleteImageAPI {
+	return MockECRBatchDeleteImageAPI(func(ctx context.Context, params *ecr.BatchDeleteImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error) {
+		if params.ImageIds == nil || len(params.ImageIds) != 1 {
+			return nil, errors.New("BatchDeleteImageを呼び出すときのImageIdsの指定が間違っています")
+		}
+		if params.RegistryId == nil || aws.ToString(params.RegistryId) != mockParams.ECRParams.RegistryId {
+			return nil, errors.New("BatchDeleteImageを呼び出すときのRegistryIdの指定が間違っています")
+		}
+		if params.RepositoryName == nil || aws.ToString(params.RepositoryName) != mockParams.ECRParams.RepositoryName {
+			return nil, errors.New("BatchDeleteImageを呼び出すときのRepositoryNameの指定が間違っています")
+		}
+
+		imageId := params.ImageIds[0]
+		imageDetail, ok := findImageDetail(mockParams, imageId)
+		if !ok {
+			deleteOutput := &ecr.BatchDeleteImageOutput{
+				Failures: []types.ImageFailure{
+					{
+						FailureCode:   types.ImageFailureCodeImageNotFound,
+						FailureReason: aws.String("Requested image not found"),
+						ImageId:       &imageId,
+					},
+				},
+			}
+			return deleteOutput, nil
+		}
+		deleteOutput := &ecr.BatchDeleteImageOutput{
+			ImageIds: []types.ImageIdentifier{
+				{
+					ImageDigest: imageDetail.ImageDigest,
+					ImageTag:    imageId.ImageTag,
+				},
+			},
+		}
+		return deleteOutput, nil
+	})
+}
+
+func GenerateMockECRDescribeRepositoriesAPI(mockParams MockECRParams) MockECRDescribeRepositoriesAPI {
+	return MockECRDescribeRepositoriesAPI(func(ctx context.Context, params *ecr.DescribeRepositoriesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
+		if params.RegistryId == nil || aws.ToString(params.RegistryId) != mockParams.ECRParams.RegistryId {
+			return nil, errors.New("DescribeRepositoriesを呼び出すときのRegistryIdの指定が間違っています")
+		}
+		if len(params.RepositoryNames) != 1 || params.RepositoryNames[0] != mockParams.ECRParams.RepositoryName {
+			return nil, &types.RepositoryNotFoundException{Message: aws.String("The repository does not exist in the registry.")}
+		}
+
+		imageTagMutability := mockParams.ECRParams.ImageTagMutability
+		if imageTagMutability == "" {
+			imageTagMutability = types.ImageTagMutabilityMutable
+		}
+		repositoryOutput := &ecr.DescribeRepositoriesOutput{
+			Repositories: []types.Repository{
+				{
+					RepositoryName:     aws.String(mockParams.ECRParams.RepositoryName),
+					RegistryId:         aws.String(mockParams.ECRParams.RegistryId),
+					ImageTagMutability: imageTagMutability,
+				},
+			},
+		}
+		return repositoryOutput, nil
+	})
+}